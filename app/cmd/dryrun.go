@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andresbott/substrans/internal/llmtranslate"
+	"github.com/andresbott/substrans/internal/report"
+	"github.com/andresbott/substrans/internal/subsedit"
+)
+
+// dryRunStats describes the work of a translation run
+type dryRunStats struct {
+	items   int
+	skipped int
+	// requests is the amount of texts sent to the model, items with several lines need more than one
+	requests int
+	// cachedItems are items whose texts are all in the cache
+	cachedItems int
+	characters  int
+	tokens      int
+	largest     int
+	// overflows is the amount of requests that do not fit in the context window
+	overflows int
+}
+
+// dryRun builds the prompt of every item and prints the amount of work and the estimated time of the
+// translation, items are only translated if a calibration is requested and nothing is written
+func dryRun(editor *subsedit.Editor, translator *llmtranslate.Translator, cache *llmtranslate.Cache, opts translateOpts, log *slog.Logger) error {
+	stats := dryRunStats{}
+	selected := editor.Selected()
+	stats.items = len(selected)
+	stats.skipped = editor.GetTotalItems() - stats.items
+
+	uncached := map[int]bool{}
+	err := forEachRequest(editor, opts.contextSize, func(index int, prev, next []string, text, speaker string) error {
+		stats.requests++
+		stats.characters += utf8.RuneCountInString(text)
+		if _, ok := translator.MemoryMatch(text, opts.targetLanguage); ok {
+			return nil
+		}
+		if cache != nil {
+			key, err := translator.CacheKey(prev, next, text, speaker, opts.targetLanguage)
+			if err != nil {
+				return err
+			}
+			if _, ok := cache.Get(key); ok {
+				return nil
+			}
+		}
+		uncached[index] = true
+		tokens, err := translator.PromptTokens(prev, next, text, speaker, opts.targetLanguage)
+		if err != nil {
+			return err
+		}
+		stats.tokens += tokens
+		stats.largest = max(stats.largest, tokens)
+		if !translator.FitsContext(tokens) {
+			stats.overflows++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	stats.cachedItems = stats.items - len(uncached)
+
+	fmt.Println("Dry run, no output is written")
+	fmt.Printf("Items: %d to translate, %d skipped by the style filter\n", stats.items, stats.skipped)
+	if cache != nil || opts.memory != "" {
+		fmt.Printf("Cached: %d items are already in the cache or the translation memory\n", stats.cachedItems)
+	}
+	fmt.Printf("Requests: %d with %d characters\n", stats.requests, stats.characters)
+	fmt.Printf("Prompt tokens: %d, the largest prompt has %d\n", stats.tokens, stats.largest)
+	if stats.overflows > 0 {
+		fmt.Printf("Context window: %d prompts do not fit in %d tokens with the reply, their context will be shrunk\n", stats.overflows, translator.ContextLength())
+	}
+
+	average, source, err := averageItemDuration(editor, selected, translator, opts, log)
+	if err != nil {
+		return err
+	}
+	if average == 0 {
+		fmt.Println("Estimated time: unknown, use --calibrate or a --report of a previous run")
+		return nil
+	}
+	estimate := editor.Estimate(average, len(uncached))
+	fmt.Printf("Estimated time: %s with %d workers, %s per item %s\n", estimate.Round(time.Second), opts.workers, average.Round(time.Millisecond), source)
+	return nil
+}
+
+// averageItemDuration returns the time needed to translate an item, measured by translating the first items if
+// a calibration is requested, otherwise taken from the report of a previous run; 0 if neither is available
+func averageItemDuration(editor *subsedit.Editor, selected []int, translator *llmtranslate.Translator, opts translateOpts, log *slog.Logger) (time.Duration, string, error) {
+	if opts.calibrate > 0 {
+		it := newItemTranslator(translator, opts.targetTag, opts.langPolicy, log)
+		n := min(opts.calibrate, len(selected))
+		if n == 0 {
+			return 0, "", nil
+		}
+		start := time.Now()
+		for _, i := range selected[:n] {
+			err := editor.ReplaceLineAt(i, opts.contextSize, it.translateItem)
+			if err != nil {
+				return 0, "", fmt.Errorf("calibration failed: %v", err)
+			}
+		}
+		return time.Since(start) / time.Duration(n), fmt.Sprintf("measured on %d items", n), nil
+	}
+
+	if opts.reportFile == "" {
+		return 0, "", nil
+	}
+	if _, err := os.Stat(opts.reportFile); err != nil {
+		return 0, "", nil
+	}
+	rep, err := report.Load(opts.reportFile)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read previous report: %v", err)
+	}
+	if rep.Model != opts.model {
+		log.Warn("the previous report was created with another model, the estimation might be off", "report", rep.Model, "model", opts.model)
+	}
+	var total time.Duration
+	n := 0
+	for _, item := range rep.Items {
+		if item.CacheHit || item.MemoryHit {
+			continue
+		}
+		total += time.Duration(item.DurationMs) * time.Millisecond
+		n++
+	}
+	if n == 0 {
+		return 0, "", nil
+	}
+	return total / time.Duration(n), fmt.Sprintf("from %d items of %s", n, opts.reportFile), nil
+}
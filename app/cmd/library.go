@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/andresbott/substrans/internal/langtag"
+	"github.com/andresbott/substrans/internal/sidecar"
+	"github.com/spf13/cobra"
+)
+
+func libraryCmd() *cobra.Command {
+	var (
+		languages []string
+		source    string
+		profile   string
+		dryRun    bool
+		regional  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "library <path>",
+		Short: "Translate the missing sidecar subtitles of a media library",
+		Long: `Scan a media library for videos with a subtitle in the source language but none in a target language,
+named the way Jellyfin and Plex expect them, e.g. "Episode.en.srt", and write the translation next to it as
+"Episode.es.srt". The .forced, .sdh, .hi and .cc flags of the source name are kept in the translated name.
+The region of the target language is only added to the name with --regional-names, e.g. "Episode.es-MX.srt".`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, log, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if len(languages) == 0 {
+				return fmt.Errorf("at least one target language must be specified")
+			}
+			sourceTag, err := parseLibraryLanguage(source)
+			if err != nil {
+				return fmt.Errorf("invalid source language: %v", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			failed := 0
+			for _, lang := range languages {
+				targetTag, err := parseLibraryLanguage(lang)
+				if err != nil {
+					return fmt.Errorf("invalid target language: %v", err)
+				}
+				missing, err := sidecar.Scan(args[0], sourceTag, targetTag, regional)
+				if err != nil {
+					return fmt.Errorf("failed to scan library: %v", err)
+				}
+				fmt.Printf("%d subtitles without %s translation\n", len(missing), targetTag.Name())
+				for _, m := range missing {
+					if dryRun {
+						fmt.Printf("  %s -> %s\n", m.Source, m.Output)
+						continue
+					}
+					if ctx.Err() != nil {
+						return nil
+					}
+					opts, err := configuredOpts(translateOpts{
+						inputFile:      m.Source,
+						outputFile:     m.Output,
+						targetLanguage: targetTag.String(),
+						sourceLanguage: sourceTag.String(),
+						profile:        profile,
+						ctx:            ctx,
+					}, cfg, log)
+					if err != nil {
+						return err
+					}
+					err = runTranslate(opts, log)
+					if err != nil {
+						if ctx.Err() != nil {
+							return nil
+						}
+						failed++
+						log.Error("translation failed", "file", m.Source, "err", err)
+					}
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d translations failed", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&languages, "language", "l", nil, "target languages, can be repeated")
+	cmd.Flags().StringVar(&source, "source-language", "en", "language of the subtitles to translate as found in the file names")
+	cmd.Flags().StringVarP(&profile, "profile", "p", "", "name of a profile defined in the config file")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list the subtitles that would be translated")
+	cmd.Flags().BoolVar(&regional, "regional-names", false, "add the region of the target language to the file names, e.g. Episode.es-MX.srt")
+	return cmd
+}
+
+// parseLibraryLanguage accepts the three letter codes used in file names besides the codes and names of translate
+func parseLibraryLanguage(s string) (langtag.Tag, error) {
+	if tag, ok := langtag.ParseAlpha3(strings.TrimSpace(s)); ok {
+		return tag, nil
+	}
+	return langtag.Parse(s)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andresbott/substrans/app/config"
+	"github.com/andresbott/substrans/internal/llmserver"
+	"github.com/andresbott/substrans/internal/llmtranslate"
+	"github.com/spf13/cobra"
+)
+
+func modelsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "List, pull and probe the models of the configured backend",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listModels(cmd)
+		},
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List the models available on the backend",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return listModels(cmd)
+			},
+		},
+		&cobra.Command{
+			Use:   "pull <model>",
+			Short: "Download a model, only supported by ollama",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return pullModel(cmd, args[0])
+			},
+		},
+		probeCmd(),
+	)
+	return cmd
+}
+
+// newServerClient creates a client for the backend of the configuration
+func newServerClient(cmd *cobra.Command) (*llmserver.Client, config.AppCfg, error) {
+	cfg, _, err := loadConfig(cmd)
+	if err != nil {
+		return nil, cfg, err
+	}
+	client, err := llmserver.NewClient(cfg.Backend.Type, backendUrl(cfg.Backend), cfg.Backend.ApiKey)
+	return client, cfg, err
+}
+
+func listModels(cmd *cobra.Command) error {
+	client, _, err := newServerClient(cmd)
+	if err != nil {
+		return err
+	}
+	models, err := client.List(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list models: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tMODIFIED\tCONTEXT\tTEMPERATURE\tWORKERS\tJSON")
+	for _, m := range models {
+		size, modified := "-", "-"
+		if m.Size > 0 {
+			size = fmt.Sprintf("%.1f GB", float64(m.Size)/1e9)
+		}
+		if !m.Modified.IsZero() {
+			modified = m.Modified.Format("2006-01-02")
+		}
+		info, known := llmtranslate.LookupModel(m.Name)
+		if !known {
+			fmt.Fprintf(w, "%s\t%s\t%s\t-\t-\t-\t-\n", m.Name, size, modified)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.1f\t%d\t%v\n", m.Name, size, modified, info.ContextLength, info.Temperature, info.BatchSize, info.JSONMode)
+	}
+	return w.Flush()
+}
+
+func pullModel(cmd *cobra.Command, name string) error {
+	client, _, err := newServerClient(cmd)
+	if err != nil {
+		return err
+	}
+	status := ""
+	err = client.Pull(context.Background(), name, func(p llmserver.Progress) {
+		if p.Status != status && status != "" {
+			fmt.Println()
+		}
+		status = p.Status
+		if p.Total > 0 {
+			fmt.Printf("\r%s %3d%%", p.Status, p.Completed*100/p.Total)
+			return
+		}
+		fmt.Printf("\r%s", p.Status)
+	})
+	fmt.Println()
+	return err
+}
+
+func probeCmd() *cobra.Command {
+	language := ""
+	cmd := &cobra.Command{
+		Use:   "probe <model>",
+		Short: "Translate a short line to measure the latency of a model and check if json mode works",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, log, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			opts := translateOpts{model: args[0], backend: cfg.Backend, targetLanguage: language}
+			opts.applyModelDefaults(log)
+			translator, err := newTranslator(opts)
+			if err != nil {
+				return err
+			}
+			res, err := translator.Probe(context.Background(), language)
+			if err != nil {
+				return fmt.Errorf("probe failed: %v", err)
+			}
+			fmt.Printf("Translation: %s\n", res.Translation)
+			fmt.Printf("Latency: %s\n", res.Latency.Round(time.Millisecond))
+			if res.JSONMode {
+				fmt.Println("JSON mode: works")
+			} else {
+				fmt.Printf("JSON mode: not working (%s)\n", res.JSONError)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&language, "language", "l", "spanish", "language of the probe translation")
+	return cmd
+}
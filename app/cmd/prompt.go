@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/andresbott/substrans/internal/llmtranslate"
+	"github.com/spf13/cobra"
+)
+
+// promptDump writes the chat sent to the model and its reply for every request to a directory
+type promptDump struct {
+	dir string
+
+	mu sync.Mutex
+	// requests counts the requests of every item, items with several lines or retries send more than one
+	requests map[int]int
+}
+
+func newPromptDump(dir string) (*promptDump, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prompt dump directory: %v", err)
+	}
+	return &promptDump{dir: dir, requests: map[int]int{}}, nil
+}
+
+// write stores the exchange as item-0012.txt, readable, and item-0012.json, the body of an ollama chat request;
+// further requests of the same item are suffixed, e.g. item-0012-2.txt
+func (d *promptDump) write(index int, ex llmtranslate.Exchange) error {
+	d.mu.Lock()
+	d.requests[index]++
+	n := d.requests[index]
+	d.mu.Unlock()
+
+	name := fmt.Sprintf("item-%04d", index)
+	if n > 1 {
+		name += "-" + strconv.Itoa(n)
+	}
+	err := os.WriteFile(filepath.Join(d.dir, name+".txt"), []byte(ex.String()), 0o644)
+	if err != nil {
+		return err
+	}
+	body, err := ex.ChatRequest()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(d.dir, name+".json"), body, 0o644)
+}
+
+func promptCmd() *cobra.Command {
+	opts := translateOpts{}
+	renderOnly := false
+	cmd := &cobra.Command{
+		Use:   "prompt <index>",
+		Short: "Print the prompt of a single subtitle item and the raw reply of the model",
+		Long: `Print the system prompt and messages sent to translate the item at index, as numbered in the
+translation report, followed by the raw reply of the model.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			index, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid item index %q", args[0])
+			}
+			log, err := opts.load(cmd)
+			if err != nil {
+				return err
+			}
+			editor, translator, err := prepareTranslation(&opts, log)
+			if err != nil {
+				return err
+			}
+			if index < 0 || index >= editor.GetTotalItems() {
+				return fmt.Errorf("item %d does not exist, the file has %d items", index, editor.GetTotalItems())
+			}
+
+			ctx := context.Background()
+			return forItemRequests(editor, index, opts.contextSize, func(prev, next []string, text, speaker string) error {
+				var ex llmtranslate.Exchange
+				var err error
+				if renderOnly {
+					ex, err = translator.Render(prev, next, text, speaker, opts.targetLanguage)
+				} else {
+					ex, err = translator.Exchange(ctx, prev, next, text, speaker, opts.targetLanguage)
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Println(ex.String())
+				return nil
+			})
+		},
+	}
+	addTranslateFlags(cmd, &opts)
+	cmd.Flags().BoolVar(&renderOnly, "render-only", false, "only print the prompt without sending it to the model")
+	return cmd
+}
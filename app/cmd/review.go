@@ -0,0 +1,473 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andresbott/substrans/app/logger"
+	"github.com/andresbott/substrans/internal/llmtranslate"
+	"github.com/andresbott/substrans/internal/report"
+	"github.com/andresbott/substrans/internal/subsedit"
+	"github.com/asticode/go-astisub"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+func reviewCmd() *cobra.Command {
+	opts := translateOpts{}
+	reportFile := ""
+	cmd := &cobra.Command{
+		Use:   "review <input> <output>",
+		Short: "Review a translation side by side, edit or re-translate items and save the result",
+		Long: `Review a translated subtitle file next to its original. Items are listed with their timing and the
+warnings and quality scores of the translation report; single items can be edited inline or translated
+again with a hint for the model. Press ? for the keys. When the input is not a terminal a line based
+mode reading commands is used instead, type h for its list of commands.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log, err := opts.load(cmd)
+			if err != nil {
+				return err
+			}
+			opts.inputFile, opts.outputFile = args[0], args[1]
+
+			var rep *report.Report
+			if reportFile != "" {
+				r, err := report.Load(reportFile)
+				if err != nil {
+					return fmt.Errorf("failed to load report: %v", err)
+				}
+				rep = &r
+				// the languages and model of the run are used to translate again
+				if opts.targetLanguage == "" {
+					opts.targetLanguage = r.Language
+				}
+				if opts.sourceLanguage == "" {
+					opts.sourceLanguage = r.SourceLanguage
+				}
+				if !cmd.Flags().Changed("model") && r.Model != "" {
+					opts.model = r.Model
+				}
+			}
+			err = opts.validate()
+			if err != nil {
+				return err
+			}
+			// the terminal ui is used unless the input is piped, e.g. by a script
+			interactive := isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd())
+			editorLog := log
+			if interactive {
+				// the editor logs every replacement, it would break the screen of the terminal ui
+				editorLog = logger.SilentLogger()
+			}
+			editor, err := subsedit.NewWithTranslation(opts.inputFile, opts.outputFile, editorLog)
+			if err != nil {
+				return err
+			}
+			translator, err := prepareTranslator(editor, &opts, log)
+			if err != nil {
+				return err
+			}
+
+			r := newReviewer(editor, translator, opts, rep, os.Stdin, os.Stdout)
+			r.reportFile = reportFile
+			if interactive {
+				return runReviewUI(r)
+			}
+			return r.run()
+		},
+	}
+	addTranslateFlags(cmd, &opts)
+	cmd.Flags().StringVar(&reportFile, "report", "", "json report of the translation, its warnings and scores are shown and it is updated when saving")
+	return cmd
+}
+
+// reviewPageSize is the amount of items listed at once
+const reviewPageSize = 10
+
+const reviewHelp = `Commands:
+  n, enter          next page
+  p                 previous page
+  g <index>         go to an item
+  f                 toggle showing only items with warnings
+  e <index>         edit the translation of an item, use | to separate lines
+  r <index> [hint]  translate an item again, the optional hint is added to the prompt
+  s                 save the translation (and the report)
+  q                 quit
+  h                 show this help
+`
+
+// reviewer reviews a translation, it holds the actions shared by the terminal ui and the line based mode
+// reading commands used when the input is not a terminal
+type reviewer struct {
+	editor     *subsedit.Editor
+	translator *llmtranslate.Translator
+	opts       translateOpts
+	report     *report.Report
+	reportFile string
+	// reportItems are the items of the report by index
+	reportItems map[int]*report.Item
+
+	in  *bufio.Scanner
+	out io.Writer
+	// width is the width of the terminal in characters
+	width       int
+	pos         int
+	flaggedOnly bool
+	unsaved     bool
+}
+
+func newReviewer(editor *subsedit.Editor, translator *llmtranslate.Translator, opts translateOpts, rep *report.Report, in io.Reader, out io.Writer) *reviewer {
+	r := &reviewer{
+		editor:      editor,
+		translator:  translator,
+		opts:        opts,
+		report:      rep,
+		reportItems: map[int]*report.Item{},
+		in:          bufio.NewScanner(in),
+		out:         out,
+		width:       100,
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 40 {
+		r.width = cols
+	}
+	if rep != nil {
+		for i := range rep.Items {
+			r.reportItems[rep.Items[i].Index] = &rep.Items[i]
+		}
+	}
+	return r
+}
+
+func (r *reviewer) run() error {
+	fmt.Fprintf(r.out, "Reviewing %s (%d items), type h for help\n", r.opts.outputFile, r.editor.GetTotalItems())
+	r.page()
+	for {
+		cmd, arg, ok := r.prompt("review> ")
+		if !ok {
+			return nil
+		}
+		var err error
+		switch cmd {
+		case "", "n":
+			r.next()
+			r.page()
+		case "p":
+			r.pos = max(0, r.pos-reviewPageSize)
+			r.page()
+		case "g":
+			err = r.goTo(arg)
+		case "f":
+			r.flaggedOnly = !r.flaggedOnly
+			r.pos = 0
+			r.page()
+		case "e":
+			err = r.edit(arg)
+		case "r":
+			err = r.retranslate(arg)
+		case "s":
+			err = r.save()
+			if err == nil {
+				fmt.Fprintf(r.out, "saved %s\n", r.opts.outputFile)
+			}
+		case "q":
+			if !r.unsaved || r.confirm("There are unsaved changes, quit anyway? [y/N] ", false) {
+				return nil
+			}
+		case "h", "?":
+			fmt.Fprint(r.out, reviewHelp)
+		default:
+			fmt.Fprintf(r.out, "unknown command %q, type h for help\n", cmd)
+		}
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+// prompt reads a command, ok is false once the input ends
+func (r *reviewer) prompt(text string) (string, string, bool) {
+	fmt.Fprint(r.out, text)
+	if !r.in.Scan() {
+		return "", "", false
+	}
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(r.in.Text()), " ")
+	return cmd, strings.TrimSpace(arg), true
+}
+
+func (r *reviewer) confirm(text string, def bool) bool {
+	answer, _, ok := r.prompt(text)
+	if !ok || answer == "" {
+		return def
+	}
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
+
+// visible returns the indexes listed with the current filter
+func (r *reviewer) visible() []int {
+	out := []int{}
+	for i := 0; i < r.editor.GetTotalItems(); i++ {
+		if r.flaggedOnly {
+			item, ok := r.reportItems[i]
+			if !ok || len(item.Warnings) == 0 {
+				continue
+			}
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+func (r *reviewer) next() {
+	if r.pos+reviewPageSize < len(r.visible()) {
+		r.pos += reviewPageSize
+	}
+}
+
+func (r *reviewer) page() {
+	indexes := r.visible()
+	if len(indexes) == 0 {
+		fmt.Fprintln(r.out, "no items to show")
+		return
+	}
+	end := min(r.pos+reviewPageSize, len(indexes))
+	for _, i := range indexes[r.pos:end] {
+		r.show(i)
+	}
+	fmt.Fprintf(r.out, "-- %d-%d of %d --\n", r.pos+1, end, len(indexes))
+}
+
+// show prints an item with the original and the translation side by side
+func (r *reviewer) show(index int) {
+	for _, line := range r.itemView(index, r.width) {
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+// itemView returns the lines showing an item with its timing, score, the original and the translation
+// side by side in width characters and its warnings
+func (r *reviewer) itemView(index, width int) []string {
+	res, err := r.editor.ItemAt(index)
+	if err != nil {
+		return []string{fmt.Sprintf("error: %v", err)}
+	}
+	header := fmt.Sprintf("#%d  %s -> %s", index, subsedit.FormatTime(res.StartAt), subsedit.FormatTime(res.EndAt))
+	var warnings []string
+	if item, ok := r.reportItems[index]; ok {
+		if item.QAScore != nil {
+			header += fmt.Sprintf("  QA %.0f", *item.QAScore)
+		}
+		warnings = item.Warnings
+	}
+	out := []string{header}
+
+	col := (width - 5) / 2
+	left, right := wrapColumn(res.Source, col), wrapColumn(res.Target, col)
+	for i := 0; i < max(len(left), len(right)); i++ {
+		l, rt := "", ""
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			rt = right[i]
+		}
+		out = append(out, fmt.Sprintf("  %s%s | %s", l, strings.Repeat(" ", col-utf8.RuneCountInString(l)), rt))
+	}
+	for _, w := range warnings {
+		out = append(out, fmt.Sprintf("  ! %s", w))
+	}
+	return out
+}
+
+func (r *reviewer) index(arg string) (int, error) {
+	index, err := strconv.Atoi(arg)
+	if err != nil || index < 0 || index >= r.editor.GetTotalItems() {
+		return 0, fmt.Errorf("invalid item %q, use an index from 0 to %d", arg, r.editor.GetTotalItems()-1)
+	}
+	return index, nil
+}
+
+func (r *reviewer) goTo(arg string) error {
+	index, err := r.index(arg)
+	if err != nil {
+		return err
+	}
+	r.flaggedOnly = false
+	r.pos = index
+	r.page()
+	return nil
+}
+
+func (r *reviewer) edit(arg string) error {
+	index, err := r.index(arg)
+	if err != nil {
+		return err
+	}
+	r.show(index)
+	fmt.Fprint(r.out, "new translation (empty to cancel): ")
+	if !r.in.Scan() {
+		return nil
+	}
+	text := strings.TrimSpace(r.in.Text())
+	if text == "" {
+		return nil
+	}
+	return r.apply(index, splitReviewLines(text))
+}
+
+func (r *reviewer) retranslate(arg string) error {
+	indexArg, hint, _ := strings.Cut(arg, " ")
+	index, err := r.index(indexArg)
+	if err != nil {
+		return err
+	}
+	replies, err := r.propose(context.Background(), index, strings.TrimSpace(hint))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "proposed: %s\n", strings.Join(replies, " | "))
+	if !r.confirm("accept? [Y/n] ", true) {
+		return nil
+	}
+	err = r.applyTranslation(index, replies)
+	if err != nil {
+		return err
+	}
+	r.show(index)
+	return nil
+}
+
+// propose translates the texts of an item again with the hint, one reply for every text sent to the model
+func (r *reviewer) propose(ctx context.Context, index int, hint string) ([]string, error) {
+	replies := []string{}
+	err := forItemRequests(r.editor, index, r.opts.contextSize, func(prev, next []string, text, speaker string) error {
+		ex, err := r.translator.ExchangeWithHint(ctx, prev, next, text, speaker, r.opts.targetLanguage, hint)
+		if err != nil {
+			return err
+		}
+		replies = append(replies, strings.TrimSpace(ex.Reply))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("translation failed: %v", err)
+	}
+	return replies, nil
+}
+
+// applyTranslation replaces every text of an item with its reply returned by propose, like the translation
+// itself this keeps the styles and inline tags of every line
+func (r *reviewer) applyTranslation(index int, replies []string) error {
+	err := r.editor.ReplaceLineAt(index, 0, func(_ int, _ []astisub.Item, actual astisub.Item, _ []astisub.Item) ([]astisub.Line, error) {
+		lines := []astisub.Line{}
+		next := 0
+		for _, line := range actual.Lines {
+			newLine := astisub.Line{}
+			for _, item := range line.Items {
+				if item.Text != "" && next < len(replies) {
+					item.Text = replies[next]
+					next++
+				}
+				newLine.Items = append(newLine.Items, astisub.LineItem{Text: item.Text})
+			}
+			lines = append(lines, newLine)
+		}
+		if next != len(replies) {
+			return nil, fmt.Errorf("the item changed, got %d translations for %d texts", len(replies), next)
+		}
+		return lines, nil
+	})
+	if err != nil {
+		return err
+	}
+	r.updated(index)
+	return nil
+}
+
+// apply replaces the text of an item with a translation written by hand
+func (r *reviewer) apply(index int, text string) error {
+	err := r.setText(index, text)
+	if err != nil {
+		return err
+	}
+	r.show(index)
+	return nil
+}
+
+func (r *reviewer) setText(index int, text string) error {
+	err := r.editor.SetText(index, text)
+	if err != nil {
+		return err
+	}
+	r.updated(index)
+	return nil
+}
+
+// updated records that an item changed, its new text is set in the report
+func (r *reviewer) updated(index int) {
+	if item, ok := r.reportItems[index]; ok {
+		if res, err := r.editor.ItemAt(index); err == nil {
+			item.Target = res.Target
+		}
+	}
+	r.unsaved = true
+}
+
+func (r *reviewer) save() error {
+	err := r.editor.Write(r.opts.outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to save translated subtitles: %v", err)
+	}
+	if r.report != nil && r.reportFile != "" {
+		err = r.report.Write(r.reportFile)
+		if err != nil {
+			return fmt.Errorf("failed to write report: %v", err)
+		}
+	}
+	r.unsaved = false
+	return nil
+}
+
+// splitReviewLines converts the lines typed separated by | to the lines of an item
+func splitReviewLines(text string) string {
+	parts := strings.Split(text, "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return strings.Join(parts, "\n")
+}
+
+// wrapColumn splits text in lines of at most width characters, breaking at spaces when possible
+func wrapColumn(text string, width int) []string {
+	out := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		current := ""
+		for _, word := range strings.Fields(line) {
+			for utf8.RuneCountInString(word) > width {
+				if current != "" {
+					out = append(out, current)
+					current = ""
+				}
+				runes := []rune(word)
+				out = append(out, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			switch {
+			case current == "":
+				current = word
+			case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width:
+				current += " " + word
+			default:
+				out = append(out, current)
+				current = word
+			}
+		}
+		out = append(out, current)
+	}
+	return out
+}
@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reviewMode is what the review ui is doing, keys are handled differently in every mode
+type reviewMode int
+
+const (
+	modeBrowse reviewMode = iota
+	// modeEdit edits the translation of the selected item inline
+	modeEdit
+	// modeHint reads the hint of a re-translation
+	modeHint
+	// modeGoTo reads the index of the item to select
+	modeGoTo
+	// modeTranslating waits for the model to translate the selected item again
+	modeTranslating
+	// modeProposal waits for a re-translation to be accepted or rejected
+	modeProposal
+	// modeQuit confirms quitting with unsaved changes
+	modeQuit
+)
+
+const reviewUIHelp = "↑/↓ move  pgup/pgdn page  e edit  r re-translate  g go to  f only warnings  s save  q quit"
+
+var (
+	selectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	warningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	proposalStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	statusStyle   = lipgloss.NewStyle().Faint(true)
+)
+
+// proposalMsg is the result of a re-translation started by the ui
+type proposalMsg struct {
+	// request identifies the re-translation, results of cancelled ones are ignored
+	request int
+	index   int
+	replies []string
+	err     error
+}
+
+// reviewUI is the terminal ui of the review command, it lists the items and edits them through the reviewer
+type reviewUI struct {
+	r *reviewer
+	// indexes are the items listed with the current filter, cursor is the position of the selected one
+	indexes []int
+	cursor  int
+	// offset is the position of the first item shown
+	offset int
+	mode   reviewMode
+
+	textarea textarea.Model
+	input    textinput.Model
+	proposal []string
+	// proposalIndex is the item translated again
+	proposalIndex int
+	request       int
+	cancel        context.CancelFunc
+	status        string
+
+	width, height int
+}
+
+// runReviewUI starts the terminal ui and returns once the user quits
+func runReviewUI(r *reviewer) error {
+	ui := &reviewUI{r: r, width: r.width, height: 24}
+	ui.indexes = r.visible()
+	ui.textarea = textarea.New()
+	ui.textarea.ShowLineNumbers = false
+	ui.textarea.Prompt = "  "
+	ui.input = textinput.New()
+	ui.status = fmt.Sprintf("Reviewing %s (%d items), press ? for help", r.opts.outputFile, r.editor.GetTotalItems())
+
+	_, err := tea.NewProgram(ui, tea.WithAltScreen()).Run()
+	if ui.cancel != nil {
+		ui.cancel()
+	}
+	return err
+}
+
+func (ui *reviewUI) Init() tea.Cmd {
+	return nil
+}
+
+func (ui *reviewUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := ui.update(msg)
+	ui.scroll()
+	return model, cmd
+}
+
+func (ui *reviewUI) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		ui.width, ui.height = msg.Width, msg.Height
+		ui.textarea.SetWidth(max(10, ui.width/2-4))
+		return ui, nil
+	case proposalMsg:
+		if msg.request != ui.request || ui.mode != modeTranslating {
+			return ui, nil
+		}
+		ui.cancel = nil
+		if msg.err != nil {
+			ui.mode = modeBrowse
+			ui.status = fmt.Sprintf("error: %v", msg.err)
+			return ui, nil
+		}
+		ui.proposal, ui.proposalIndex = msg.replies, msg.index
+		ui.mode = modeProposal
+		ui.status = "accept the proposed translation? y/n"
+		return ui, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return ui, tea.Quit
+		}
+		switch ui.mode {
+		case modeEdit:
+			return ui.updateEdit(msg)
+		case modeHint, modeGoTo:
+			return ui.updateInput(msg)
+		case modeTranslating:
+			if msg.String() == "esc" {
+				ui.cancel()
+				ui.cancel = nil
+				ui.mode = modeBrowse
+				ui.status = "re-translation cancelled"
+			}
+			return ui, nil
+		case modeProposal:
+			return ui.updateProposal(msg)
+		case modeQuit:
+			if msg.String() == "y" {
+				return ui, tea.Quit
+			}
+			ui.mode = modeBrowse
+			ui.status = ""
+			return ui, nil
+		}
+		return ui.updateBrowse(msg)
+	}
+	return ui, nil
+}
+
+func (ui *reviewUI) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ui.status = ""
+	switch msg.String() {
+	case "up", "k":
+		ui.move(-1)
+	case "down", "j":
+		ui.move(1)
+	case "pgup":
+		ui.move(-reviewPageSize)
+	case "pgdown", " ":
+		ui.move(reviewPageSize)
+	case "home":
+		ui.move(-len(ui.indexes))
+	case "end":
+		ui.move(len(ui.indexes))
+	case "f":
+		ui.r.flaggedOnly = !ui.r.flaggedOnly
+		ui.indexes = ui.r.visible()
+		ui.cursor, ui.offset = 0, 0
+	case "e", "enter":
+		index, ok := ui.selected()
+		if !ok {
+			return ui, nil
+		}
+		res, err := ui.r.editor.ItemAt(index)
+		if err != nil {
+			ui.status = fmt.Sprintf("error: %v", err)
+			return ui, nil
+		}
+		ui.textarea.SetValue(res.Target)
+		ui.textarea.SetHeight(max(2, strings.Count(res.Target, "\n")+2))
+		ui.mode = modeEdit
+		ui.status = "editing, ctrl+s to apply, esc to cancel"
+		return ui, ui.textarea.Focus()
+	case "r":
+		if _, ok := ui.selected(); ok {
+			return ui, ui.startInput(modeHint, "hint for the model (optional): ")
+		}
+	case "g":
+		return ui, ui.startInput(modeGoTo, "go to item: ")
+	case "s":
+		err := ui.r.save()
+		if err != nil {
+			ui.status = fmt.Sprintf("error: %v", err)
+		} else {
+			ui.status = fmt.Sprintf("saved %s", ui.r.opts.outputFile)
+		}
+	case "q", "esc":
+		if ui.r.unsaved {
+			ui.mode = modeQuit
+			ui.status = "there are unsaved changes, quit anyway? y/N"
+			return ui, nil
+		}
+		return ui, tea.Quit
+	case "?", "h":
+		ui.status = reviewUIHelp
+	}
+	return ui, nil
+}
+
+func (ui *reviewUI) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		ui.textarea.Blur()
+		ui.mode = modeBrowse
+		ui.status = "edit cancelled"
+		return ui, nil
+	case "ctrl+s":
+		ui.textarea.Blur()
+		ui.mode = modeBrowse
+		index, _ := ui.selected()
+		text := strings.TrimRight(ui.textarea.Value(), "\n")
+		if err := ui.r.setText(index, text); err != nil {
+			ui.status = fmt.Sprintf("error: %v", err)
+		} else {
+			ui.status = fmt.Sprintf("item %d changed, press s to save", index)
+		}
+		return ui, nil
+	}
+	var cmd tea.Cmd
+	ui.textarea, cmd = ui.textarea.Update(msg)
+	return ui, cmd
+}
+
+func (ui *reviewUI) startInput(mode reviewMode, prompt string) tea.Cmd {
+	ui.mode = mode
+	ui.input.Prompt = prompt
+	ui.input.SetValue("")
+	ui.status = ""
+	return ui.input.Focus()
+}
+
+func (ui *reviewUI) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		ui.input.Blur()
+		ui.mode = modeBrowse
+		return ui, nil
+	case "enter":
+		ui.input.Blur()
+		value := strings.TrimSpace(ui.input.Value())
+		if ui.mode == modeGoTo {
+			ui.mode = modeBrowse
+			ui.goTo(value)
+			return ui, nil
+		}
+		return ui, ui.retranslate(value)
+	}
+	var cmd tea.Cmd
+	ui.input, cmd = ui.input.Update(msg)
+	return ui, cmd
+}
+
+// retranslate asks the model for a new translation of the selected item in the background
+func (ui *reviewUI) retranslate(hint string) tea.Cmd {
+	index, _ := ui.selected()
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.request++
+	request := ui.request
+	ui.mode = modeTranslating
+	ui.status = fmt.Sprintf("translating item %d again, esc to cancel", index)
+	return func() tea.Msg {
+		replies, err := ui.r.propose(ctx, index, hint)
+		return proposalMsg{request: request, index: index, replies: replies, err: err}
+	}
+}
+
+func (ui *reviewUI) updateProposal(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "enter":
+		index := ui.proposalIndex
+		if err := ui.r.applyTranslation(index, ui.proposal); err != nil {
+			ui.status = fmt.Sprintf("error: %v", err)
+		} else {
+			ui.status = fmt.Sprintf("item %d changed, press s to save", index)
+		}
+	case "n", "esc":
+		ui.status = "proposal rejected"
+	default:
+		return ui, nil
+	}
+	ui.proposal = nil
+	ui.mode = modeBrowse
+	return ui, nil
+}
+
+func (ui *reviewUI) selected() (int, bool) {
+	if len(ui.indexes) == 0 {
+		return 0, false
+	}
+	return ui.indexes[ui.cursor], true
+}
+
+func (ui *reviewUI) move(n int) {
+	if len(ui.indexes) == 0 {
+		return
+	}
+	ui.cursor = min(max(ui.cursor+n, 0), len(ui.indexes)-1)
+}
+
+func (ui *reviewUI) goTo(value string) {
+	index, err := ui.r.index(value)
+	if err != nil {
+		ui.status = fmt.Sprintf("error: %v", err)
+		return
+	}
+	if ui.r.flaggedOnly {
+		// the item might be hidden by the filter
+		ui.r.flaggedOnly = false
+		ui.indexes = ui.r.visible()
+	}
+	ui.cursor = index
+}
+
+// scroll moves the list so that the selected item is shown completely
+func (ui *reviewUI) scroll() {
+	if ui.cursor < ui.offset {
+		ui.offset = ui.cursor
+	}
+	for ui.offset < ui.cursor {
+		lines := 0
+		for pos := ui.offset; pos <= ui.cursor; pos++ {
+			lines += len(ui.itemLines(pos))
+		}
+		if lines <= ui.listHeight() {
+			return
+		}
+		ui.offset++
+	}
+}
+
+// footer returns the lines shown below the list: the input of the hint or index and the status
+func (ui *reviewUI) footer() []string {
+	out := []string{}
+	if ui.mode == modeHint || ui.mode == modeGoTo {
+		out = append(out, ui.input.View())
+	}
+	return append(out, statusStyle.Render(ui.statusLine()))
+}
+
+func (ui *reviewUI) listHeight() int {
+	return max(1, ui.height-len(ui.footer()))
+}
+
+func (ui *reviewUI) View() string {
+	height := ui.listHeight()
+	out := []string{}
+	if len(ui.indexes) == 0 {
+		out = append(out, "no items to show")
+	}
+	for pos := ui.offset; pos < len(ui.indexes) && len(out) < height; pos++ {
+		out = append(out, ui.itemLines(pos)...)
+	}
+	if len(out) > height {
+		out = out[:height]
+	}
+	for len(out) < height {
+		out = append(out, "")
+	}
+	return strings.Join(append(out, ui.footer()...), "\n")
+}
+
+// itemLines renders the item at a position of the list, the selected item is highlighted and shows the
+// inline editor or the proposed translation
+func (ui *reviewUI) itemLines(pos int) []string {
+	index := ui.indexes[pos]
+	lines := ui.r.itemView(index, ui.width)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "  ! ") {
+			lines[i] = warningStyle.Render(line)
+		}
+	}
+	if pos != ui.cursor {
+		return lines
+	}
+	lines[0] = selectedStyle.Render("> " + lines[0])
+	switch ui.mode {
+	case modeEdit:
+		lines = append(lines, strings.Split(ui.textarea.View(), "\n")...)
+	case modeProposal:
+		for _, reply := range ui.proposal {
+			lines = append(lines, proposalStyle.Render("  proposed: "+reply))
+		}
+	}
+	return lines
+}
+
+func (ui *reviewUI) statusLine() string {
+	if ui.status != "" {
+		return ui.status
+	}
+	if len(ui.indexes) == 0 {
+		return reviewUIHelp
+	}
+	filter := ""
+	if ui.r.flaggedOnly {
+		filter = ", only items with warnings"
+	}
+	changed := ""
+	if ui.r.unsaved {
+		changed = ", unsaved changes"
+	}
+	return fmt.Sprintf("item %d of %d%s%s, ? for help", ui.cursor+1, len(ui.indexes), filter, changed)
+}
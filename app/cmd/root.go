@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/andresbott/substrans/app/config"
+	"github.com/andresbott/substrans/app/logger"
 	"github.com/andresbott/substrans/app/metainfo"
 	"github.com/spf13/cobra"
+	"log/slog"
 	"os"
 	"runtime"
 )
@@ -23,6 +26,8 @@ func newRootCommand() *cobra.Command {
 		Short: "substrans LLM subtitle translation tool",
 	}
 
+	cmd.PersistentFlags().String("config", "", "path to a yaml or json config file")
+
 	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
 		_ = cmd.Help()
 		return nil
@@ -31,11 +36,41 @@ func newRootCommand() *cobra.Command {
 	cmd.AddCommand(
 		versionCmd(),
 		translateCmd(),
+		promptCmd(),
+		reviewCmd(),
+		serveCmd(),
+		watchCmd(),
+		libraryCmd(),
+		tmxCmd(),
+		modelsCmd(),
 	)
 
 	return cmd
 }
 
+// loadConfig loads the configuration file passed with the --config flag and creates a logger
+// with the configured log level; the messages collected while loading the config are logged.
+func loadConfig(cmd *cobra.Command) (config.AppCfg, *slog.Logger, error) {
+	file, _ := cmd.Flags().GetString("config")
+	cfg, err := config.Get(file)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	log, err := logger.GetDefault(logger.GetLogLevel(cfg.Env.LogLevel))
+	if err != nil {
+		return cfg, nil, fmt.Errorf("failed to create logger: %v", err)
+	}
+	for _, m := range cfg.Msgs {
+		if m.Level == "info" {
+			log.Info(m.Msg)
+		} else {
+			log.Debug(m.Msg)
+		}
+	}
+	return cfg, log, nil
+}
+
 func versionCmd() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "version",
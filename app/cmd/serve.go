@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/andresbott/substrans/internal/jobs"
+	"github.com/andresbott/substrans/internal/server"
+	"github.com/andresbott/substrans/internal/subsedit"
+	"github.com/spf13/cobra"
+)
+
+func serveCmd() *cobra.Command {
+	var (
+		addr      string
+		dir       string
+		queueSize int
+		workers   int
+		retention string
+		maxJobs   int
+	)
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start a REST api to translate uploaded subtitle files",
+		Long: `Start an http server to translate subtitle files in the background:
+
+  POST /jobs               upload a file (multipart field "file") with the fields language and the
+                           optional source_language, model and profile; returns the job id
+  GET  /jobs               list the jobs
+  GET  /jobs/{id}          status and progress of a job
+  GET  /jobs/{id}/result   download the translated file of a finished job
+  DELETE /jobs/{id}        delete a finished job and its files
+
+Jobs are translated with the settings of the config file, when the queue is full new uploads are rejected.
+Finished jobs and their files are deleted after the retention time or when more than max-jobs are kept.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, log, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("addr") {
+				addr = cfg.Server.Addr
+			}
+			if !cmd.Flags().Changed("dir") {
+				dir = cfg.Server.Dir
+			}
+			if !cmd.Flags().Changed("queue") {
+				queueSize = cfg.Server.QueueSize
+			}
+			if !cmd.Flags().Changed("jobs") {
+				workers = cfg.Server.Jobs
+			}
+			if !cmd.Flags().Changed("retention") {
+				retention = cfg.Server.Retention
+			}
+			if !cmd.Flags().Changed("max-jobs") {
+				maxJobs = cfg.Server.MaxJobs
+			}
+			var ttl time.Duration
+			if retention != "" && retention != "0" {
+				ttl, err = time.ParseDuration(retention)
+				if err != nil || ttl < 0 {
+					return fmt.Errorf("invalid job retention %q", retention)
+				}
+			}
+			if dir == "" {
+				dir, err = os.MkdirTemp("", "substrans-")
+				if err != nil {
+					return fmt.Errorf("unable to create work directory: %v", err)
+				}
+				defer func() { _ = os.RemoveAll(dir) }()
+			} else {
+				err = os.MkdirAll(dir, 0755)
+				if err != nil {
+					return fmt.Errorf("unable to create work directory: %v", err)
+				}
+			}
+
+			translate := func(ctx context.Context, req server.Request, progress jobs.ProgressFunc) error {
+				opts, err := configuredOpts(translateOpts{
+					inputFile:      req.Input,
+					outputFile:     req.Output,
+					targetLanguage: req.Language,
+					sourceLanguage: req.SourceLanguage,
+					profile:        req.Profile,
+					ctx:            ctx,
+					progress: func(p subsedit.Progress) {
+						progress(p.Done, p.Total, p.Remaining)
+					},
+				}, cfg, log)
+				if err != nil {
+					return err
+				}
+				if req.Model != "" {
+					opts.model = req.Model
+				}
+				return runTranslate(opts, log)
+			}
+
+			queue := jobs.NewQueue(queueSize, workers)
+			handler := server.New(queue, dir, translate, log).Handler()
+			queue.SetRetention(ttl, maxJobs)
+			srv := &http.Server{
+				Addr:              addr,
+				Handler:           handler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			errCh := make(chan error, 1)
+			go func() {
+				log.Info("starting server", "addr", addr, "dir", dir, "queue", queueSize, "jobs", workers,
+					"retention", ttl, "maxJobs", maxJobs)
+				errCh <- srv.ListenAndServe()
+			}()
+			select {
+			case err = <-errCh:
+				if !errors.Is(err, http.ErrServerClosed) {
+					return fmt.Errorf("server failed: %v", err)
+				}
+				return nil
+			case <-ctx.Done():
+			}
+			log.Info("shutting down, running translations are aborted")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = srv.Shutdown(shutdownCtx)
+			queue.Close()
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", "", "address the server listens on, e.g. 127.0.0.1:8080")
+	cmd.Flags().StringVar(&dir, "dir", "", "directory storing the uploaded and translated files, a temporary one is used if empty")
+	cmd.Flags().IntVar(&queueSize, "queue", 0, "amount of jobs waiting to be translated before new uploads are rejected")
+	cmd.Flags().IntVar(&workers, "jobs", 0, "amount of files translated at the same time")
+	cmd.Flags().StringVar(&retention, "retention", "", "time finished jobs and their files are kept, e.g. 24h, 0 keeps them")
+	cmd.Flags().IntVar(&maxJobs, "max-jobs", 0, "amount of finished jobs kept, the oldest are deleted first, 0 keeps all")
+	return cmd
+}
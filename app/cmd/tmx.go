@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/andresbott/substrans/internal/llmtranslate"
+	"github.com/andresbott/substrans/internal/tmx"
+	"github.com/spf13/cobra"
+)
+
+func tmxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tmx",
+		Short: "Exchange translations with CAT tools as TMX translation memories",
+		Long: `Export the translations of the cache to a TMX file. To use a TMX file exported by a CAT tool as a
+translation memory pass it to translate with --memory: exact matches are used without calling the model and
+fuzzy matches are sent to the model as examples.`,
+	}
+	cmd.AddCommand(tmxExportCmd())
+	return cmd
+}
+
+func tmxExportCmd() *cobra.Command {
+	var (
+		cache  string
+		output string
+		source string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the translations of the cache as a TMX file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("cache") {
+				cache = cfg.Translate.Cache
+			}
+			if cache == "" || output == "" {
+				return fmt.Errorf("cache and output files must be specified")
+			}
+			c, err := llmtranslate.OpenCache(cache)
+			if err != nil {
+				return fmt.Errorf("failed to open cache: %v", err)
+			}
+			doc, err := llmtranslate.ExportTMX(c.Entries(), source)
+			if err != nil {
+				return fmt.Errorf("%v, use --source-language", err)
+			}
+			err = tmx.Write(output, doc)
+			if err != nil {
+				return fmt.Errorf("failed to write tmx: %v", err)
+			}
+			fmt.Printf("Exported %d translation units to %s\n", len(doc.Units), output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&cache, "cache", "", "json cache written by translate, defaults to the cache of the config file")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "TMX file to write")
+	cmd.Flags().StringVar(&source, "source-language", "", "source language of entries stored without it by older versions")
+	return cmd
+}
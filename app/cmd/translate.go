@@ -1,89 +1,816 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"github.com/andresbott/substrans/app/logger"
+	"github.com/andresbott/substrans/app/config"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 
+	"github.com/andresbott/substrans/internal/langid"
+	"github.com/andresbott/substrans/internal/langtag"
 	"github.com/andresbott/substrans/internal/llmtranslate"
+	"github.com/andresbott/substrans/internal/qa"
+	"github.com/andresbott/substrans/internal/report"
 	"github.com/andresbott/substrans/internal/subsedit"
 	"github.com/asticode/go-astisub"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// translateOpts holds the flags of the translate command
+type translateOpts struct {
+	inputFile      string
+	outputFile     string
+	targetLanguage string
+	sourceLanguage string
+	model          string
+	workers        int
+	glossary       string
+	systemPrompt   string
+	userPrompt     string
+	cache          string
+	memory         string
+	examples       string
+	exampleCount   int
+	showNotes      string
+	formality      string
+	tone           string
+	reflow         bool
+	maxLines       int
+	maxWidth       int
+	retime         bool
+	langPolicy     string
+	qaMode         string
+	qaReport       string
+	qaRatio        float64
+	reportFile     string
+	reportHTML     string
+	profile        string
+	force          bool
+	dryRun         bool
+	calibrate      int
+	dumpPrompts    string
+	items          string
+	onlyFlagged    string
+
+	// targetTag is the parsed target language, targetLanguage holds its description for the prompt
+	targetTag langtag.Tag
+
+	// the following are only set from the config file
+	backend     config.Backend
+	temperature float64
+	// temperatureSet is true if the temperature is configured, otherwise the recommendation for the model is used
+	temperatureSet bool
+	contextSize    int
+	fuzzyScore     float64
+	voices         []llmtranslate.Voice
+	filter         subsedit.StyleFilter
+	outputPattern  string
+
+	// progress is optional, it is called after every translated item
+	progress func(subsedit.Progress)
+	// ctx cancels the translation when it is started by serve or watch, it is not needed on the command line
+	ctx context.Context
+}
+
+// applyConfig sets the options from the configuration, values of flags set on the command line take precedence
+func (o *translateOpts) applyConfig(cfg config.AppCfg, flags *pflag.FlagSet) {
+	if !flags.Changed("model") {
+		o.model = cfg.Translate.Model
+	}
+	if !flags.Changed("workers") {
+		o.workers = cfg.Translate.Workers
+	}
+	if !flags.Changed("glossary") {
+		o.glossary = cfg.Translate.Glossary
+	}
+	if !flags.Changed("system-prompt") {
+		o.systemPrompt = cfg.Translate.SystemPrompt
+	}
+	if !flags.Changed("prompt") {
+		o.userPrompt = cfg.Translate.UserPrompt
+	}
+	if !flags.Changed("examples") {
+		o.examples = cfg.Translate.Examples
+	}
+	if !flags.Changed("examples-count") {
+		o.exampleCount = cfg.Translate.ExampleCount
+	}
+	if !flags.Changed("notes") {
+		o.showNotes = cfg.Translate.Notes
+	}
+	if !flags.Changed("formality") {
+		o.formality = cfg.Translate.Formality
+	}
+	if !flags.Changed("tone") {
+		o.tone = cfg.Translate.Tone
+	}
+	if !flags.Changed("cache") {
+		o.cache = cfg.Translate.Cache
+	}
+	if !flags.Changed("memory") {
+		o.memory = cfg.Translate.Memory
+	}
+	o.backend = cfg.Backend
+	o.temperature = cfg.Translate.Temperature
+	o.temperatureSet = cfg.Translate.TemperatureSet
+	o.contextSize = cfg.Translate.ContextSize
+	o.fuzzyScore = cfg.Translate.FuzzyScore
+	o.filter = subsedit.StyleFilter{
+		Include: cfg.Filter.IncludeStyles,
+		Exclude: cfg.Filter.ExcludeStyles,
+	}
+	o.outputPattern = cfg.Output.Pattern
+}
+
+// default values used when neither the configuration nor the model registry set them
+const (
+	defaultTemperature = 0.3
+	defaultWorkers     = 1
+)
+
+// applyModelDefaults sets the temperature and workers that were not configured to the values recommended for the
+// model, the chosen values are logged since known models on ollama translate several items in parallel
+func (o *translateOpts) applyModelDefaults(log *slog.Logger) {
+	info, known := llmtranslate.LookupModel(o.model)
+	if !o.temperatureSet {
+		o.temperature = defaultTemperature
+		if known {
+			o.temperature = info.Temperature
+		}
+	}
+	if o.workers == 0 {
+		o.workers = defaultWorkers
+		if known && (o.backend.Type == "" || o.backend.Type == llmtranslate.BackendOllama) {
+			o.workers = info.BatchSize
+		}
+		log.Info("using the recommended workers for the model, set them with --workers", "model", o.model, "workers", o.workers)
+	}
+}
+
+// outputPath returns the output file, if it was not specified it is created from the output pattern
+func (o *translateOpts) outputPath() (string, error) {
+	if o.outputFile != "" {
+		return o.outputFile, nil
+	}
+	tmpl, err := template.New("output").Parse(o.outputPattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid output pattern: %v", err)
+	}
+	ext := filepath.Ext(o.inputFile)
+	data := map[string]string{
+		"Dir":  filepath.Dir(o.inputFile),
+		"Name": strings.TrimSuffix(filepath.Base(o.inputFile), ext),
+		"Ext":  ext,
+		"Lang": o.targetTag.String(),
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid output pattern: %v", err)
+	}
+	return buf.String(), nil
+}
+
 func translateCmd() *cobra.Command {
-	var inputFile string
-	var outputFile string
-	var targetLanguage string
-	var model string
+	opts := translateOpts{}
 
 	cmd := &cobra.Command{
 		Use:   "translate",
 		Short: "Translate subtitles to another language",
 		Long:  `Translate a video subtitle file to another language using the specified target language.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if inputFile == "" || targetLanguage == "" {
-				return fmt.Errorf("input file and target language must be specified")
-
+			log, err := opts.load(cmd)
+			if err != nil {
+				return err
 			}
-			fmt.Printf("Translating %s to %s and saving to %s\n", inputFile, targetLanguage, outputFile)
+			return runTranslate(opts, log)
+		},
+	}
+	addTranslateFlags(cmd, &opts)
+	cmd.Flags().StringVarP(&opts.outputFile, "output", "o", "", "Output subtitle file")
+	cmd.Flags().StringVar(&opts.cache, "cache", "", "json file storing translations to reuse them in later runs")
+	cmd.Flags().BoolVar(&opts.reflow, "reflow", false, "re-wrap translated text into balanced lines")
+	cmd.Flags().IntVar(&opts.maxLines, "max-lines", 2, "maximum lines per subtitle when reflowing")
+	cmd.Flags().IntVar(&opts.maxWidth, "max-width", 42, "maximum characters per line when reflowing")
+	cmd.Flags().BoolVar(&opts.retime, "retime", false, "split long subtitles at sentence boundaries and merge short consecutive ones")
+	cmd.Flags().StringVar(&opts.qaMode, "qa", "", "quality estimation mode, supported: backtranslate")
+	cmd.Flags().StringVar(&opts.qaReport, "qa-report", "", "path of the quality report, defaults to the output file with .qa.json extension")
+	cmd.Flags().Float64Var(&opts.qaRatio, "qa-ratio", 0.05, "ratio of items with the lowest quality score to flag for review")
+	cmd.Flags().StringVar(&opts.reportFile, "report", "", "write a json report of the translation to this path")
+	cmd.Flags().StringVar(&opts.reportHTML, "report-html", "", "write an html page comparing source and translation to this path")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "count the items and tokens to translate and estimate the time needed without writing any output")
+	cmd.Flags().IntVar(&opts.calibrate, "calibrate", 0, "with --dry-run, translate this amount of items to estimate the time, otherwise the durations of a previous --report are used")
+	cmd.Flags().StringVar(&opts.dumpPrompts, "dump-prompts", "", "write the prompts sent to the model and its raw replies to this directory, ignored with --dry-run")
+	cmd.Flags().StringVar(&opts.items, "items", "", "only translate these items again in the existing output, e.g. 12,40-45")
+	cmd.Flags().StringVar(&opts.onlyFlagged, "only-flagged", "", "only translate again the items with warnings in this report, the report is updated with the new translations")
 
-			ollamaURL := os.Getenv("OLLAMA_HOST")
+	return cmd
+}
 
-			if model == "" {
-				model = llmtranslate.ModelLlama31
-			}
+// load reads the configuration and the profile into the options, flags set on the command line take precedence
+func (o *translateOpts) load(cmd *cobra.Command) (*slog.Logger, error) {
+	cfg, log, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = o.applyProfile(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	o.applyConfig(cfg, cmd.Flags())
+	return log, nil
+}
 
-			translator, err := llmtranslate.NewTranslator(model, ollamaURL, 0.3)
-			if err != nil {
-				return fmt.Errorf("failed to create translator: %v", err)
+// configuredOpts completes the options of a translation started by serve or watch, there are no
+// command line flags so all the values come from the configuration and the profile
+func configuredOpts(opts translateOpts, cfg config.AppCfg, log *slog.Logger) (translateOpts, error) {
+	cfg, err := opts.applyProfile(cfg, log)
+	if err != nil {
+		return opts, err
+	}
+	opts.applyConfig(cfg, pflag.NewFlagSet("config", pflag.ContinueOnError))
+	return opts, nil
+}
 
-			}
+// applyProfile returns the configuration with the selected profile merged over it and sets
+// the language and voices of the profile in the options
+func (o *translateOpts) applyProfile(cfg config.AppCfg, log *slog.Logger) (config.AppCfg, error) {
+	if o.profile == "" {
+		return cfg, nil
+	}
+	cfg, profile, err := cfg.ApplyProfile(o.profile)
+	if err != nil {
+		return cfg, err
+	}
+	if o.targetLanguage == "" {
+		o.targetLanguage = profile.Language
+	}
+	for _, c := range profile.Characters {
+		o.voices = append(o.voices, llmtranslate.Voice{Speaker: c.Name, Formality: c.Formality, Tone: c.Tone})
+	}
+	log.Info("using profile", "name", profile.Name)
+	return cfg, nil
+}
 
-			log, err := logger.GetDefault(slog.LevelInfo)
-			if err != nil {
-				return fmt.Errorf("failed to create logger: %v", err)
-			}
+// addTranslateFlags adds the flags that configure how items are translated
+func addTranslateFlags(cmd *cobra.Command, opts *translateOpts) {
+	cmd.Flags().StringVarP(&opts.inputFile, "input", "i", "", "Input subtitle file")
+	cmd.Flags().StringVarP(&opts.targetLanguage, "language", "l", "", "Target language for translation")
+	cmd.Flags().StringVarP(&opts.model, "model", "m", "", "model to use")
+	cmd.Flags().StringVarP(&opts.profile, "profile", "p", "", "name of a profile defined in the config file")
+	cmd.Flags().IntVar(&opts.workers, "workers", 0, "amount of subtitles translated in parallel, 0 uses the recommendation for the model")
+	cmd.Flags().StringVar(&opts.glossary, "glossary", "", "file with fixed translations, one \"term = translation\" per line")
+	cmd.Flags().StringVar(&opts.systemPrompt, "system-prompt", "", "go template file replacing the built-in system prompt")
+	cmd.Flags().StringVar(&opts.userPrompt, "prompt", "", "go template file replacing the built-in prompt of every line")
+	cmd.Flags().StringVar(&opts.examples, "examples", "", "json file with reference translations sent to the model before every line")
+	cmd.Flags().IntVar(&opts.exampleCount, "examples-count", 0, "only send the examples most similar to the translated line, 0 sends all of them")
+	cmd.Flags().StringVar(&opts.memory, "memory", "", "TMX translation memory, exact matches are used as translation and fuzzy matches are sent to the model")
+	cmd.Flags().StringVar(&opts.showNotes, "notes", "", "json file with the synopsis, characters and relationships of the show")
+	cmd.Flags().StringVar(&opts.formality, "formality", "", "register used to address people: formal, informal or auto")
+	cmd.Flags().StringVar(&opts.tone, "tone", "", "free form description of the tone of the translation, e.g. \"casual, this is a comedy\"")
+	cmd.Flags().StringVar(&opts.sourceLanguage, "source-language", "", "language of the input subtitles, detected from the text if empty")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "translate even if the source and target languages are the same")
+	cmd.Flags().StringVar(&opts.langPolicy, "verify-language", "", "verify the language of each translation, action on mismatch: retry, keep (the original) or flag")
+}
 
-			editor, err := subsedit.New(inputFile, log)
-			if err != nil {
-				return fmt.Errorf("failed to create subtitle editor: %v", err)
-			}
+func runTranslate(opts translateOpts, log *slog.Logger) error {
+	started := time.Now()
+	editor, translator, err := prepareTranslation(&opts, log)
+	if err != nil {
+		return err
+	}
+	if opts.dryRun {
+		fmt.Printf("Planning the translation of %s to %s\n", opts.inputFile, opts.targetLanguage)
+	} else {
+		fmt.Printf("Translating %s to %s and saving to %s\n", opts.inputFile, opts.targetLanguage, opts.outputFile)
+	}
 
-			callback := func(prevItems []astisub.Item, actualItem astisub.Item, nextItems []astisub.Item) ([]astisub.Line, error) {
-				return translateCallback(prevItems, actualItem, nextItems, translator, targetLanguage)
-			}
+	it := newItemTranslator(translator, opts.targetTag, opts.langPolicy, log)
+	if opts.ctx != nil {
+		it.ctx = opts.ctx
+	}
+	if opts.cache != "" {
+		it.cache, err = llmtranslate.OpenCache(opts.cache)
+		if err != nil {
+			return fmt.Errorf("failed to open cache: %v", err)
+		}
+	}
+	// a dry run writes nothing, not even the dump directory
+	if opts.dumpPrompts != "" && !opts.dryRun {
+		it.dump, err = newPromptDump(opts.dumpPrompts)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.dryRun {
+		return dryRun(editor, translator, it.cache, opts, log)
+	}
+	if opts.progress != nil {
+		editor.SetProgress(opts.progress)
+	}
+	err = editor.IterateAndReplaceAt(opts.contextSize, it.translateItem)
+	if it.cache != nil {
+		// the cache is saved also on errors so that an interrupted run can be resumed
+		cacheErr := it.cache.Save()
+		if cacheErr != nil {
+			log.Error("failed to save cache", "err", cacheErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to translate subtitles %v", err)
+	}
+
+	rep := report.New(editor.Results(), it.notes)
+	rep.Input = opts.inputFile
+	rep.Output = opts.outputFile
+	rep.Language = opts.targetTag.String()
+	rep.SourceLanguage = opts.sourceLanguage
+	rep.Model = opts.model
+	rep.Profile = opts.profile
+	rep.Prompt = translator.PromptHash()
+	rep.StartedAt = started
+	if opts.partial() {
+		err = mergePreviousReport(&rep, opts)
+		if err != nil {
+			return err
+		}
+		if opts.reportFile == "" {
+			opts.reportFile = opts.onlyFlagged
+		}
+	}
+
+	if opts.qaMode == qa.ModeBackTranslate {
+		err = backTranslate(it.ctx, editor, translator, opts.targetLanguage, opts.sourceLanguage, opts.qaRatio, opts.qaReport, &rep)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.reflow {
+		editor.Reflow(subsedit.ReflowOptions{
+			MaxLines: opts.maxLines,
+			MaxWidth: opts.maxWidth,
+			Lang:     opts.targetTag.Language,
+		})
+	}
+
+	if opts.retime {
+		editor.Retime(subsedit.RetimeOptions{
+			Wrap: subsedit.ReflowOptions{
+				MaxLines: opts.maxLines,
+				MaxWidth: opts.maxWidth,
+				Lang:     opts.targetTag.Language,
+			},
+		})
+	}
+
+	err = editor.Write(opts.outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to save translated subtitles: %v", err)
+	}
+
+	rep.DurationMs = time.Since(started).Milliseconds()
+	usage := translator.Usage()
+	rep.PromptTokens = usage.PromptTokens
+	rep.CompletionTokens = usage.CompletionTokens
+	rep.ContextShrunk = usage.Shrunk
+	if usage.Shrunk > 0 {
+		log.Warn("context lines were dropped to fit the context window of the model", "requests", usage.Shrunk)
+	}
+	fmt.Printf("Tokens used: %d prompt, %d completion in %d requests\n", usage.PromptTokens, usage.CompletionTokens, usage.Requests)
+	err = writeReports(rep, opts.reportFile, opts.reportHTML)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Translation completed successfully.")
+	return nil
+}
+
+// validate checks the options and resolves the target and source languages and the output path
+func (o *translateOpts) validate() error {
+	if o.inputFile == "" || o.targetLanguage == "" {
+		return fmt.Errorf("input file and target language must be specified")
+	}
+	if o.langPolicy != "" && o.langPolicy != langPolicyRetry && o.langPolicy != langPolicyKeep && o.langPolicy != langPolicyFlag {
+		return fmt.Errorf("unsupported language verification policy: %s", o.langPolicy)
+	}
+	if o.qaMode != "" && o.qaMode != qa.ModeBackTranslate {
+		return fmt.Errorf("unsupported qa mode: %s", o.qaMode)
+	}
+	if o.retime && o.partial() {
+		return fmt.Errorf("--retime cannot be used with --items or --only-flagged, the items would no longer match the original")
+	}
+	tag, err := langtag.Parse(o.targetLanguage)
+	if err != nil {
+		return fmt.Errorf("invalid target language: %v", err)
+	}
+	o.targetTag = tag
+	o.targetLanguage = tag.Name()
+	if o.sourceLanguage != "" {
+		sourceTag, err := langtag.Parse(o.sourceLanguage)
+		if err != nil {
+			return fmt.Errorf("invalid source language: %v", err)
+		}
+		o.sourceLanguage = sourceTag.Name()
+	}
+	outputFile, err := o.outputPath()
+	if err != nil {
+		return err
+	}
+	o.outputFile = outputFile
+	if o.qaMode != "" && o.qaReport == "" {
+		o.qaReport = strings.TrimSuffix(o.outputFile, filepath.Ext(o.outputFile)) + ".qa.json"
+	}
+	return nil
+}
+
+// prepareTranslation validates the options, loads the input and creates the translator, the options are
+// updated with the resolved languages, output path and model defaults
+func prepareTranslation(opts *translateOpts, log *slog.Logger) (*subsedit.Editor, *llmtranslate.Translator, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, nil, err
+	}
+	var editor *subsedit.Editor
+	if opts.partial() {
+		editor, err = openPartial(*opts, log)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		editor, err = subsedit.New(opts.inputFile, log)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create subtitle editor: %v", err)
+		}
+	}
+	translator, err := prepareTranslator(editor, opts, log)
+	if err != nil {
+		return nil, nil, err
+	}
+	return editor, translator, nil
+}
+
+// partial returns true if only some items of an existing translation are translated again
+func (o *translateOpts) partial() bool {
+	return o.items != "" || o.onlyFlagged != ""
+}
+
+// mergePreviousReport adds the items that were not translated again from the previous report, the one passed
+// to --only-flagged or else the existing report file
+func mergePreviousReport(rep *report.Report, opts translateOpts) error {
+	path := opts.onlyFlagged
+	if path == "" {
+		path = opts.reportFile
+		if path == "" {
+			return nil
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	previous, err := report.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load report: %v", err)
+	}
+	rep.Merge(previous)
+	return nil
+}
+
+// openPartial loads the original and the existing translation and selects the items to translate again
+func openPartial(opts translateOpts, log *slog.Logger) (*subsedit.Editor, error) {
+	editor, err := subsedit.NewWithTranslation(opts.inputFile, opts.outputFile, log)
+	if errors.Is(err, subsedit.ErrItemsMismatch) {
+		return nil, fmt.Errorf("%s cannot be updated item by item, it was written with --retime or edited: %v; "+
+			"translate the whole file again", opts.outputFile, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the existing translation: %v", err)
+	}
+	selection := []int{}
+	if opts.items != "" {
+		selection, err = subsedit.ParseSelection(opts.items, editor.GetTotalItems())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.onlyFlagged != "" {
+		rep, err := report.Load(opts.onlyFlagged)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load report: %v", err)
+		}
+		flagged := rep.Flagged()
+		if len(flagged) == 0 {
+			return nil, fmt.Errorf("the report %s has no items with warnings", opts.onlyFlagged)
+		}
+		for _, item := range flagged {
+			selection = append(selection, item.Index)
+		}
+	}
+	err = editor.SetSelection(selection)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("translating selected items again", "items", len(editor.Selected()))
+	return editor, nil
+}
+
+// prepareTranslator resolves the source language and creates the translator for the items of the editor
+func prepareTranslator(editor *subsedit.Editor, opts *translateOpts, log *slog.Logger) (*llmtranslate.Translator, error) {
+	var err error
+	opts.sourceLanguage, err = resolveSourceLanguage(editor, opts.sourceLanguage, opts.targetLanguage, opts.force, log)
+	if err != nil {
+		return nil, err
+	}
+	if opts.sourceLanguage == "" && opts.qaMode != "" {
+		return nil, fmt.Errorf("the source language is needed for the quality estimation, set it with --source-language")
+	}
+	opts.applyModelDefaults(log)
+
+	translator, err := newTranslator(*opts)
+	if err != nil {
+		return nil, err
+	}
+	editor.SetLanguage(opts.targetTag.String())
+	editor.SetWorkers(opts.workers)
+	editor.SetStyleFilter(opts.filter)
+	if info, ok := translator.ModelInfo(); ok {
+		err = checkContextWindow(editor, translator, info, *opts, log)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Debug("model not in the registry, using generic defaults", "model", opts.model)
+	}
 
-			err = editor.IterateAndReplace(10, callback)
+	return translator, nil
+}
+
+// minDetectConfidence is the confidence needed to use a detected source language
+const minDetectConfidence = 0.9
+
+// detectSample is the amount of subtitle items used to detect the source language
+const detectSample = 200
+
+// resolveSourceLanguage detects the source language if none is given and verifies that it differs from the target,
+// an empty language is returned if it can't be detected
+func resolveSourceLanguage(editor *subsedit.Editor, source, target string, force bool, log *slog.Logger) (string, error) {
+	if source == "" {
+		texts := []string{}
+		for i := 0; i < editor.GetTotalItems() && i < detectSample; i++ {
+			_, item, _, err := editor.ContextAt(i, 0)
 			if err != nil {
-				return fmt.Errorf("failed to translate subtitles %v", err)
+				return "", err
 			}
+			texts = append(texts, subsedit.SpeakerText([]astisub.Item{item})...)
+		}
+		// speakers are removed again, they are mostly names and don't help detecting the language
+		for i, text := range texts {
+			if _, after, found := strings.Cut(text, ": "); found {
+				texts[i] = after
+			}
+		}
+		code, confidence := langid.Detect(strings.Join(texts, "\n"))
+		if code == "" || confidence < minDetectConfidence {
+			log.Warn("unable to detect the source language, set it with --source-language")
+			return "", nil
+		}
+		tag, err := langtag.Parse(code)
+		if err != nil {
+			log.Warn("detected source language is not supported", "code", code)
+			return "", nil
+		}
+		source = tag.Name()
+		log.Info("detected source language", "language", source, "confidence", confidence)
+	}
+
+	same, regional := langid.SameLanguage(source, target)
+	if !same {
+		return source, nil
+	}
+	if regional {
+		log.Warn("source and target are variants of the same language", "source", source, "target", target)
+		return source, nil
+	}
+	if force {
+		log.Warn("source and target languages are the same", "language", source)
+		return source, nil
+	}
+	return "", fmt.Errorf("source and target languages are the same (%s), use --force to translate anyway", source)
+}
 
-			err = editor.Write(outputFile)
+// checkContextWindow warns if the prompt of any item would not fit in the context window of the model
+// and if the model is not known to be good at the target language
+func checkContextWindow(editor *subsedit.Editor, translator *llmtranslate.Translator, info llmtranslate.ModelInfo, opts translateOpts, log *slog.Logger) error {
+	if !info.StrongIn(opts.targetTag.Language) {
+		log.Warn("the model is not known to translate well into the target language", "model", info.Name, "language", opts.targetLanguage)
+	}
+
+	largest, largestIndex := 0, 0
+	err := forEachRequest(editor, opts.contextSize, func(index int, prev, next []string, text, speaker string) error {
+		tokens, err := translator.PromptTokens(prev, next, text, speaker, opts.targetLanguage)
+		if err != nil {
+			return err
+		}
+		if tokens > largest {
+			largest, largestIndex = tokens, index
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !translator.FitsContext(largest) {
+		log.Warn("the prompt of some items exceeds the context window of the model, context lines will be dropped for them",
+			"item", largestIndex, "tokens", largest, "context length", translator.ContextLength(), "context size", opts.contextSize)
+	}
+	return nil
+}
+
+// forEachRequest calls fn for every text of the selected items that is sent to the model, with the
+// same context and speaker used by the translation
+func forEachRequest(editor *subsedit.Editor, contextSize int, fn func(index int, prev, next []string, text, speaker string) error) error {
+	for _, i := range editor.Selected() {
+		err := forItemRequests(editor, i, contextSize, func(prev, next []string, text, speaker string) error {
+			return fn(i, prev, next, text, speaker)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forItemRequests calls fn for every text of the item at index that is sent to the model
+func forItemRequests(editor *subsedit.Editor, index, contextSize int, fn func(prev, next []string, text, speaker string) error) error {
+	prevItems, actual, nextItems, err := editor.ContextAt(index, contextSize)
+	if err != nil {
+		return err
+	}
+	prev, next := subsedit.SpeakerText(prevItems), subsedit.SpeakerText(nextItems)
+	for _, line := range actual.Lines {
+		for _, item := range line.Items {
+			if item.Text == "" {
+				continue
+			}
+			err = fn(prev, next, item.Text, line.VoiceName)
 			if err != nil {
-				return fmt.Errorf("failed to save translated subtitles: %v", err)
+				return err
 			}
+		}
+	}
+	return nil
+}
 
-			fmt.Println("Translation completed successfully.")
-			return nil
-		},
+// backendUrl returns the configured url of the backend, the OLLAMA_HOST env is used
+// if no url is configured for an ollama backend
+func backendUrl(backend config.Backend) string {
+	if backend.Url == "" && (backend.Type == "" || backend.Type == llmtranslate.BackendOllama) {
+		return os.Getenv("OLLAMA_HOST")
 	}
+	return backend.Url
+}
 
-	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input subtitle file")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output subtitle file")
-	cmd.Flags().StringVarP(&targetLanguage, "language", "l", "", "Target language for translation")
-	cmd.Flags().StringVarP(&model, "model", "m", "", "model to use")
+// newTranslator creates a translator for the configured backend
+func newTranslator(o translateOpts) (*llmtranslate.Translator, error) {
+	url := backendUrl(o.backend)
+	model := o.model
+	if model == "" {
+		model = llmtranslate.ModelLlama31
+	}
 
-	return cmd
+	opts := []llmtranslate.Option{
+		llmtranslate.WithBackend(o.backend.Type),
+		llmtranslate.WithAPIKey(o.backend.ApiKey),
+		llmtranslate.WithTone(o.tone),
+		llmtranslate.WithFormality(o.formality),
+		llmtranslate.WithVoices(o.voices),
+		llmtranslate.WithSourceLanguage(o.sourceLanguage),
+	}
+	if o.examples != "" {
+		examples, err := llmtranslate.LoadExamples(o.examples)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load examples: %v", err)
+		}
+		opts = append(opts, llmtranslate.WithExamples(examples, o.exampleCount))
+	}
+	if o.memory != "" {
+		memory, err := llmtranslate.LoadMemory(o.memory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load translation memory: %v", err)
+		}
+		opts = append(opts, llmtranslate.WithMemory(memory, o.fuzzyScore, 0))
+	}
+	if o.showNotes != "" {
+		notes, err := llmtranslate.LoadShowNotes(o.showNotes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load show notes: %v", err)
+		}
+		opts = append(opts, llmtranslate.WithShowNotes(notes))
+	}
+	if o.systemPrompt != "" || o.userPrompt != "" {
+		prompt, err := llmtranslate.LoadPrompt(o.systemPrompt, o.userPrompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt templates: %v", err)
+		}
+		opts = append(opts, llmtranslate.WithPrompt(prompt))
+	}
+	if o.glossary != "" {
+		glossary, err := llmtranslate.LoadGlossary(o.glossary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load glossary: %v", err)
+		}
+		opts = append(opts, llmtranslate.WithGlossary(glossary))
+	}
+
+	translator, err := llmtranslate.NewTranslator(model, url, o.temperature, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create translator: %v", err)
+	}
+	return translator, nil
+}
+
+// writeReports writes the json and html reports if a path is given
+func writeReports(rep report.Report, jsonPath, htmlPath string) error {
+	if jsonPath != "" {
+		err := rep.Write(jsonPath)
+		if err != nil {
+			return fmt.Errorf("failed to write report: %v", err)
+		}
+		fmt.Printf("Report saved to %s, %d items with warnings\n", jsonPath, len(rep.Flagged()))
+	}
+	if htmlPath != "" {
+		err := rep.WriteHTML(htmlPath)
+		if err != nil {
+			return fmt.Errorf("failed to write html report: %v", err)
+		}
+		fmt.Printf("HTML report saved to %s\n", htmlPath)
+	}
+	return nil
+}
+
+// language verification policies applied when a translation is not in the target language
+const (
+	langPolicyRetry = "retry"
+	langPolicyKeep  = "keep"
+	langPolicyFlag  = "flag"
+)
+
+// langRetries is the amount of additional attempts made with the retry policy
+const langRetries = 2
+
+// itemTranslator translates the text of subtitle items, optionally verifying the language of the results
+type itemTranslator struct {
+	translator     *llmtranslate.Translator
+	targetLanguage string
+	// targetCode is the language code used to verify translations, verification is disabled if empty
+	targetCode string
+	langPolicy string
+	log        *slog.Logger
+	// cache is optional, translations found in it are not sent to the model
+	cache *llmtranslate.Cache
+	// dump is optional, it stores the prompts and replies of every request
+	dump *promptDump
+	ctx  context.Context
+
+	mu    sync.Mutex
+	notes map[int]report.Notes
 }
 
-func translateCallback(prevItems []astisub.Item, actualItem astisub.Item, nextItems []astisub.Item, translator *llmtranslate.Translator, targetLanguage string) ([]astisub.Line, error) {
-	ctx := context.Background()
-	prevContext := extractText(prevItems)
-	postContext := extractText(nextItems)
+func newItemTranslator(translator *llmtranslate.Translator, target langtag.Tag, langPolicy string, log *slog.Logger) *itemTranslator {
+	it := &itemTranslator{
+		translator:     translator,
+		targetLanguage: target.Name(),
+		langPolicy:     langPolicy,
+		log:            log,
+		notes:          map[int]report.Notes{},
+		ctx:            context.Background(),
+	}
+	if langPolicy != "" {
+		if langid.Supported(target.Language) {
+			it.targetCode = target.Language
+		} else {
+			log.Warn("target language not supported by the language verification, skipping it", "language", target.String())
+		}
+	}
+	return it
+}
+
+func (it *itemTranslator) translateItem(index int, prevItems []astisub.Item, actualItem astisub.Item, nextItems []astisub.Item) ([]astisub.Line, error) {
+	ctx := it.ctx
+	prevContext := subsedit.SpeakerText(prevItems)
+	postContext := subsedit.SpeakerText(nextItems)
 	var translatedLines []astisub.Line
+	notes := report.Notes{}
 
 	for _, line := range actualItem.Lines {
 
@@ -94,7 +821,7 @@ func translateCallback(prevItems []astisub.Item, actualItem astisub.Item, nextIt
 				newLine.Items = append(newLine.Items, astisub.LineItem{Text: ""})
 				continue
 			}
-			translatedText, err := translator.Translate(ctx, prevContext, postContext, item.Text, targetLanguage)
+			translatedText, err := it.translateText(ctx, index, prevContext, postContext, item.Text, line.VoiceName, &notes)
 			if err != nil {
 				return nil, err
 			}
@@ -102,18 +829,143 @@ func translateCallback(prevItems []astisub.Item, actualItem astisub.Item, nextIt
 		}
 		translatedLines = append(translatedLines, newLine)
 	}
+
+	it.mu.Lock()
+	it.notes[index] = notes
+	it.mu.Unlock()
 	return translatedLines, nil
 }
 
-// Helper function to extract text from subtitle items
-func extractText(items []astisub.Item) []string {
-	texts := []string{}
-	for _, item := range items {
-		for _, line := range item.Lines {
-			for _, lineItem := range line.Items {
-				texts = append(texts, lineItem.Text)
+// translateText translates a single text, using the cache if available
+func (it *itemTranslator) translateText(ctx context.Context, index int, prevContext, postContext []string, text, speaker string, notes *report.Notes) (string, error) {
+	if translated, ok := it.translator.MemoryMatch(text, it.targetLanguage); ok {
+		notes.MemoryHit = true
+		return translated, nil
+	}
+	var key string
+	var err error
+	if it.cache != nil {
+		key, err = it.translator.CacheKey(prevContext, postContext, text, speaker, it.targetLanguage)
+		if err != nil {
+			return "", err
+		}
+		if e, ok := it.cache.Get(key); ok {
+			notes.CacheHit = true
+			return e.Target, nil
+		}
+	}
+
+	translated, err := it.verifiedTranslate(ctx, index, prevContext, postContext, text, speaker, notes)
+	if err != nil {
+		return "", err
+	}
+	if it.cache != nil {
+		it.cache.Put(key, llmtranslate.CacheEntry{
+			Source:     text,
+			Target:     translated,
+			Lang:       it.targetLanguage,
+			SourceLang: it.translator.SourceLanguage(),
+			Model:      it.translator.Model(),
+			Prompt:     it.translator.PromptHash(),
+		})
+	}
+	return translated, nil
+}
+
+// verifiedTranslate translates a text and applies the language policy to the result
+func (it *itemTranslator) verifiedTranslate(ctx context.Context, index int, prevContext, postContext []string, text, speaker string, notes *report.Notes) (string, error) {
+	translated, err := it.translate(ctx, index, prevContext, postContext, text, speaker)
+	if err != nil {
+		return "", err
+	}
+	if it.targetCode == "" {
+		return translated, nil
+	}
+
+	langErr := langid.Check(text, translated, it.targetCode)
+	if it.langPolicy == langPolicyRetry {
+		for i := 0; i < langRetries && langErr != nil; i++ {
+			it.log.Debug("retrying translation", "text", text, "reason", langErr)
+			notes.Retries++
+			translated, err = it.translate(ctx, index, prevContext, postContext, text, speaker)
+			if err != nil {
+				return "", err
 			}
+			langErr = langid.Check(text, translated, it.targetCode)
+		}
+	}
+	if langErr == nil {
+		return translated, nil
+	}
+
+	if it.langPolicy == langPolicyKeep {
+		it.log.Warn("keeping original text", "text", text, "translation", translated, "reason", langErr)
+		notes.Warnings = append(notes.Warnings, fmt.Sprintf("original text kept: %v", langErr))
+		return text, nil
+	}
+	it.log.Warn("translation flagged", "text", text, "translation", translated, "reason", langErr)
+	notes.Warnings = append(notes.Warnings, langErr.Error())
+	return translated, nil
+}
+
+// translate sends a text to the model, the prompt and reply are written to the dump directory if enabled
+func (it *itemTranslator) translate(ctx context.Context, index int, prevContext, postContext []string, text, speaker string) (string, error) {
+	if it.dump == nil {
+		return it.translator.TranslateAs(ctx, prevContext, postContext, text, speaker, it.targetLanguage)
+	}
+	ex, err := it.translator.Exchange(ctx, prevContext, postContext, text, speaker, it.targetLanguage)
+	if err != nil {
+		return "", err
+	}
+	err = it.dump.write(index, ex)
+	if err != nil {
+		it.log.Warn("failed to dump prompt", "item", index, "err", err)
+	}
+	return ex.Reply, nil
+}
+
+// backTranslate scores every translated item by translating it back to the source language and writes
+// the lowest scored items to a report, scores and flags are also added to the translation report
+func backTranslate(ctx context.Context, editor *subsedit.Editor, translator *llmtranslate.Translator, language, sourceLanguage string, ratio float64, reportPath string, rep *report.Report) error {
+	entries, err := qa.BackTranslate(ctx, translator, editor.Results(), language, sourceLanguage)
+	if err != nil {
+		return fmt.Errorf("failed to back-translate subtitles: %v", err)
+	}
+	// items kept from the previous report of a partial run are ranked as well, so that an item translated
+	// again is only flagged if it is still among the worst of the whole file
+	translated := map[int]bool{}
+	for _, e := range entries {
+		translated[e.Index] = true
+	}
+	others := []float64{}
+	for _, item := range rep.Items {
+		if item.QAScore != nil && !translated[item.Index] {
+			others = append(others, *item.QAScore)
+		}
+	}
+	qa.FlagAmong(entries, others, ratio)
+	qaReport := qa.NewReport(qa.ModeBackTranslate, sourceLanguage, entries)
+
+	err = qaReport.Write(reportPath)
+	if err != nil {
+		return fmt.Errorf("failed to write quality report: %v", err)
+	}
+	fmt.Printf("Quality report with %d flagged items saved to %s, mean score: %.1f\n", len(qaReport.Flagged), reportPath, qaReport.MeanScore)
+
+	scores := map[int]qa.Entry{}
+	for _, e := range entries {
+		scores[e.Index] = e
+	}
+	for i, item := range rep.Items {
+		e, ok := scores[item.Index]
+		if !ok {
+			continue
+		}
+		score := e.Score
+		rep.Items[i].QAScore = &score
+		if e.Flagged {
+			rep.Items[i].Warnings = append(rep.Items[i].Warnings, fmt.Sprintf("low back-translation score: %.1f", e.Score))
 		}
 	}
-	return texts
+	return nil
 }
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/andresbott/substrans/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+func watchCmd() *cobra.Command {
+	var (
+		languages []string
+		profile   string
+		interval  string
+		statePath string
+		once      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "watch <dir>",
+		Short: "Translate the subtitle files added to a folder",
+		Long: `Poll a folder and its subfolders for new or changed subtitle files and translate them into the languages
+of the first matching rule of the config file, or the languages given with --language. The translations are written
+next to the files using the output pattern, finished work is recorded in a state file so that it is not repeated
+after a restart; failed translations are only retried when the file changes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, log, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			dir := args[0]
+			if !cmd.Flags().Changed("language") {
+				languages = cfg.Watch.Languages
+			}
+			if !cmd.Flags().Changed("profile") {
+				profile = cfg.Watch.Profile
+			}
+			if !cmd.Flags().Changed("interval") {
+				interval = cfg.Watch.Interval
+			}
+			if !cmd.Flags().Changed("state") {
+				statePath = cfg.Watch.State
+			}
+			if statePath == "" {
+				statePath = filepath.Join(dir, ".substrans-watch.json")
+			}
+			every, err := time.ParseDuration(interval)
+			if err != nil || every <= 0 {
+				return fmt.Errorf("invalid watch interval %q", interval)
+			}
+
+			rules := []watch.Rule{}
+			for _, r := range cfg.Watch.Rules {
+				rules = append(rules, watch.Rule{Pattern: r.Pattern, Languages: r.Languages, Profile: r.Profile})
+			}
+			if len(languages) > 0 {
+				rules = append(rules, watch.Rule{Languages: languages, Profile: profile})
+			}
+			if len(rules) == 0 {
+				return fmt.Errorf("no languages to translate to, use --language or configure watch rules")
+			}
+
+			translate := func(ctx context.Context, req watch.Request) (string, error) {
+				opts, err := configuredOpts(translateOpts{
+					inputFile:      req.Input,
+					targetLanguage: req.Language,
+					profile:        req.Profile,
+					ctx:            ctx,
+				}, cfg, log)
+				if err != nil {
+					return "", err
+				}
+				// the output path is resolved first to leave existing translations untouched
+				resolved := opts
+				err = resolved.validate()
+				if err != nil {
+					return "", err
+				}
+				if _, err := os.Stat(resolved.outputFile); err == nil {
+					log.Info("translation already exists, skipping", "file", resolved.outputFile)
+					return resolved.outputFile, nil
+				}
+				opts.outputFile = resolved.outputFile
+				return opts.outputFile, runTranslate(opts, log)
+			}
+
+			w, err := watch.New(dir, rules, statePath, translate, log)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if once {
+				err = w.Scan(ctx)
+				if err != nil && ctx.Err() == nil {
+					return err
+				}
+				return nil
+			}
+			log.Info("watching folder", "dir", dir, "interval", every, "state", statePath)
+			return w.Run(ctx, every)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&languages, "language", "l", nil, "target languages of the files matching no rule, can be repeated")
+	cmd.Flags().StringVarP(&profile, "profile", "p", "", "profile used for the files matching no rule")
+	cmd.Flags().StringVar(&interval, "interval", "", "time between two scans of the folder, e.g. 30s")
+	cmd.Flags().StringVar(&statePath, "state", "", "file recording the finished translations, defaults to .substrans-watch.json in the folder")
+	cmd.Flags().BoolVar(&once, "once", false, "scan the folder once and exit, files modified in the last 30 seconds are left for the next run")
+	return cmd
+}
@@ -1,17 +1,119 @@
 package config
 
 import (
+	"fmt"
+	"reflect"
+
 	"github.com/go-bumbu/config"
 )
 
 type AppCfg struct {
-	Env  Env
-	Msgs []Msg
+	Env       Env       `config:"env"`
+	Backend   Backend   `config:"backend"`
+	Translate Translate `config:"translate"`
+	Filter    Filter    `config:"filter"`
+	Output    Output    `config:"output"`
+	Server    Server    `config:"server"`
+	Watch     Watch     `config:"watch"`
+	Profiles  []Profile `config:"profiles"`
+	Msgs      []Msg
 }
 
 type Env struct {
-	LogLevel   string
-	Production bool
+	LogLevel   string `config:"logLevel"`
+	Production bool   `config:"production"`
+}
+
+// Backend configures the LLM server used for translations
+type Backend struct {
+	// Type is the kind of server: ollama or openai (or any openai compatible api)
+	Type string `config:"type"`
+	// Url of the server, the OLLAMA_HOST env is used for ollama if empty
+	Url    string `config:"url"`
+	ApiKey string `config:"apiKey"`
+}
+
+// Translate holds the settings of the translation itself
+type Translate struct {
+	Model string `config:"model"`
+	// Temperature of the model, the recommendation for the model is used if it is not set
+	Temperature float64 `config:"temperature"`
+	// TemperatureSet is true if the temperature is set in the file or env, so that 0 can be configured
+	TemperatureSet bool `config:"-"`
+	// ContextSize is the amount of previous and next subtitle items sent to the model along the translated line
+	ContextSize int `config:"contextSize"`
+	// Workers is the amount of items translated in parallel, 0 uses the recommendation for the model
+	Workers int `config:"workers"`
+	// Glossary is the path to a file with fixed translations for specific terms
+	Glossary string `config:"glossary"`
+	// SystemPrompt and UserPrompt are paths to go templates replacing the built-in prompt
+	SystemPrompt string `config:"systemPrompt"`
+	UserPrompt   string `config:"userPrompt"`
+	// Examples is the path of a json file with reference translations sent to the model before every line
+	Examples string `config:"examples"`
+	// ExampleCount limits the examples to the ones most similar to the translated line, 0 sends all of them
+	ExampleCount int `config:"exampleCount"`
+	// Formality of the translated speech: formal, informal or auto, empty leaves it to the model
+	Formality string `config:"formality"`
+	// Tone is a free form description of the tone of the translation, by default the tone of the original is kept
+	Tone string `config:"tone"`
+	// Notes is the path of a json file with the synopsis, characters and relationships of the show
+	Notes string `config:"notes"`
+	// Cache is the path of a json file storing translations to reuse them in later runs
+	Cache string `config:"cache"`
+	// Memory is the path of a TMX translation memory, exact matches are used without calling the model
+	// and fuzzy matches are sent to it as examples
+	Memory string `config:"memory"`
+	// FuzzyScore is the minimum similarity from 0 to 1 of a fuzzy match of the memory, 0 uses 0.75
+	FuzzyScore float64 `config:"fuzzyScore"`
+}
+
+// Filter selects which subtitle items are translated based on their style, e.g. to leave songs or signs untouched
+type Filter struct {
+	IncludeStyles []string `config:"includeStyles"`
+	ExcludeStyles []string `config:"excludeStyles"`
+}
+
+// Output configures how translated files are written
+type Output struct {
+	// Pattern is a go template for the output file path used when none is specified,
+	// available fields: .Dir .Name .Ext and .Lang
+	Pattern string `config:"pattern"`
+}
+
+// Server configures the REST api started with the serve command
+type Server struct {
+	Addr string `config:"addr"`
+	// Dir stores the uploaded and translated files, a temporary directory is used if empty
+	Dir string `config:"dir"`
+	// QueueSize is the amount of jobs waiting to be translated, new jobs are rejected when it is full
+	QueueSize int `config:"queueSize"`
+	// Jobs is the amount of files translated at the same time
+	Jobs int `config:"jobs"`
+	// Retention is how long finished jobs and their files are kept, e.g. 24h; 0 keeps them until they are deleted
+	Retention string `config:"retention"`
+	// MaxJobs is the amount of finished jobs kept, the oldest ones are removed first; 0 keeps all
+	MaxJobs int `config:"maxJobs"`
+}
+
+// Watch configures the folder translated by the watch command
+type Watch struct {
+	// Interval between two scans of the folder, e.g. 30s or 5m
+	Interval string `config:"interval"`
+	// State is the file recording the finished translations, defaults to .substrans-watch.json in the folder
+	State string `config:"state"`
+	// Rules select the languages and profile by the file name, the first matching rule is used
+	Rules []WatchRule `config:"rules"`
+	// Languages and Profile are used for the subtitle files that match no rule, these are skipped if empty
+	Languages []string `config:"languages"`
+	Profile   string   `config:"profile"`
+}
+
+// WatchRule translates the files whose name matches a glob pattern, e.g. "*.en.srt"
+type WatchRule struct {
+	Pattern   string   `config:"pattern"`
+	Languages []string `config:"languages"`
+	Profile   string   `config:"profile"`
 }
 
 // Default represents the basic set of sensible defaults
@@ -20,6 +122,88 @@ var defaultCfg = AppCfg{
 		LogLevel:   "info",
 		Production: true,
 	},
+	Backend: Backend{
+		Type: "ollama",
+	},
+	Translate: Translate{
+		Model:       "llama3.1:8b",
+		ContextSize: 10,
+	},
+	Output: Output{
+		Pattern: "{{.Dir}}/{{.Name}}.{{.Lang}}{{.Ext}}",
+	},
+	Server: Server{
+		Addr:      "127.0.0.1:8080",
+		QueueSize: 20,
+		Jobs:      1,
+		Retention: "24h",
+		MaxJobs:   100,
+	},
+	Watch: Watch{
+		Interval: "1m",
+	},
+}
+
+// Profile holds the settings used for a specific show or language, the values set
+// in a profile are merged over the rest of the configuration
+type Profile struct {
+	Name string `config:"name"`
+	// Language is the target language of the translation
+	Language string `config:"language"`
+	// Tone describes the tone of the translation, e.g. "casual, this is a comedy"
+	Tone      string    `config:"tone"`
+	Formality string    `config:"formality"`
+	Translate Translate `config:"translate"`
+	Filter    Filter    `config:"filter"`
+	// Characters override the formality and tone for the lines of specific characters
+	Characters []CharacterVoice `config:"characters"`
+}
+
+// CharacterVoice sets how a character speaks, the name is matched against the speaker of the subtitles
+// and the characters of the show notes
+type CharacterVoice struct {
+	Name      string `config:"name"`
+	Formality string `config:"formality"`
+	Tone      string `config:"tone"`
+}
+
+// ApplyProfile returns a copy of the configuration with the values of the named profile merged over it
+func (c AppCfg) ApplyProfile(name string) (AppCfg, Profile, error) {
+	for _, p := range c.Profiles {
+		if p.Name != name {
+			continue
+		}
+		// the tone and formality of the profile apply unless its translate section sets them too
+		if p.Tone != "" {
+			c.Translate.Tone = p.Tone
+		}
+		if p.Formality != "" {
+			c.Translate.Formality = p.Formality
+		}
+		mergeSet(reflect.ValueOf(&c.Translate).Elem(), reflect.ValueOf(p.Translate))
+		mergeSet(reflect.ValueOf(&c.Filter).Elem(), reflect.ValueOf(p.Filter))
+		// a temperature of 0 is a value too when the profile sets it
+		if p.Translate.TemperatureSet {
+			c.Translate.Temperature = p.Translate.Temperature
+		}
+		return c, p, nil
+	}
+	return c, Profile{}, fmt.Errorf("profile %q not found in the configuration", name)
+}
+
+// mergeSet copies the fields of src that are not zero over dst, nested structs are merged field by field
+func mergeSet(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		if field.Kind() == reflect.Struct {
+			mergeSet(dst.Field(i), field)
+			continue
+		}
+		if field.IsZero() || (field.Kind() == reflect.Slice && field.Len() == 0) {
+			continue
+		}
+		dst.Field(i).Set(field)
+	}
 }
 
 type Msg struct {
@@ -27,13 +211,14 @@ type Msg struct {
 	Msg   string
 }
 
+// Get loads the configuration from the defaults, the optional file and env vars prefixed with BUMBU,
+// e.g. BUMBU_TRANSLATE_MODEL overrides translate.model
 func Get(file string) (AppCfg, error) {
 	configMsg := []Msg{}
 	cfg := AppCfg{}
 	var err error
-	_, err = config.Load(
+	opts := []any{
 		config.Defaults{Item: defaultCfg},
-		config.CfgFile{Path: file, Mandatory: false},
 		config.EnvVar{Prefix: "BUMBU"},
 		config.Unmarshal{Item: &cfg},
 		config.Writer{Fn: func(level, msg string) {
@@ -44,7 +229,21 @@ func Get(file string) (AppCfg, error) {
 				configMsg = append(configMsg, Msg{Level: "debug", Msg: msg})
 			}
 		}},
-	)
+	}
+	if file != "" {
+		opts = append(opts, config.CfgFile{Path: file, Mandatory: true})
+	}
+	handler, err := config.Load(opts...)
 	cfg.Msgs = configMsg
-	return cfg, err
+	if err != nil {
+		return cfg, err
+	}
+	// a temperature of 0 is a valid value, the handler tells if it was set at all
+	_, err = handler.GetString("translate.temperature")
+	cfg.Translate.TemperatureSet = err == nil
+	for i := range cfg.Profiles {
+		_, err = handler.GetString(fmt.Sprintf("profiles.%d.translate.temperature", i))
+		cfg.Profiles[i].Translate.TemperatureSet = err == nil
+	}
+	return cfg, nil
 }
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fill sets every field of a struct to a value that is not zero
+func fill(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString("profile " + v.Type().Field(i).Name)
+		case reflect.Int:
+			f.SetInt(int64(i + 1))
+		case reflect.Float64:
+			f.SetFloat(float64(i) + 0.5)
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Slice:
+			f.Set(reflect.ValueOf([]string{v.Type().Field(i).Name}))
+		case reflect.Struct:
+			fill(f)
+		}
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	p := Profile{Name: "show"}
+	fill(reflect.ValueOf(&p.Translate).Elem())
+	fill(reflect.ValueOf(&p.Filter).Elem())
+	cfg := defaultCfg
+	cfg.Profiles = []Profile{p}
+
+	// every setting of the profile must be merged, a new field that is ignored fails here
+	got, _, err := cfg.ApplyProfile("show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(p.Translate, got.Translate); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if diff := cmp.Diff(p.Filter, got.Filter); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	// unset values keep the configuration and a temperature of 0 set in the profile is applied
+	cfg.Translate.Model = "llama3.1:8b"
+	cfg.Translate.Temperature = 0.3
+	cfg.Profiles = []Profile{{Name: "show", Tone: "solemn", Translate: Translate{TemperatureSet: true}}}
+	got, _, err = cfg.ApplyProfile("show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Translate.Model != "llama3.1:8b" || got.Translate.Temperature != 0 || got.Translate.Tone != "solemn" {
+		t.Errorf("unexpected merge: %+v", got.Translate)
+	}
+
+	if _, _, err := cfg.ApplyProfile("missing"); err == nil {
+		t.Errorf("expected an error for an unknown profile")
+	}
+}
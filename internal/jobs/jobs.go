@@ -0,0 +1,329 @@
+// Package jobs runs long tasks in the background with a bounded queue and keeps track of their progress
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status of a job
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// ErrQueueFull is returned by Submit when no more jobs can be queued
+var ErrQueueFull = errors.New("the job queue is full")
+
+// ErrClosed is returned by Submit after the queue was closed
+var ErrClosed = errors.New("the job queue is closed")
+
+// ErrNotFound is returned by Remove for unknown jobs
+var ErrNotFound = errors.New("job not found")
+
+// ErrActive is returned by Remove for jobs that did not finish yet
+var ErrActive = errors.New("the job did not finish yet")
+
+// Job describes the state of a submitted task
+type Job struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	// Done and Total are the items processed by the task so far, Total is 0 until the task reports progress
+	Done        int        `json:"done"`
+	Total       int        `json:"total"`
+	RemainingMs int64      `json:"remaining_ms"`
+	Error       string     `json:"error,omitempty"`
+	Created     time.Time  `json:"created"`
+	Started     *time.Time `json:"started,omitempty"`
+	Finished    *time.Time `json:"finished,omitempty"`
+}
+
+// ProgressFunc is called by a task to report its progress
+type ProgressFunc func(done, total int, remaining time.Duration)
+
+// Task is the work of a job, the context is cancelled when the queue is closed
+type Task func(ctx context.Context, progress ProgressFunc) error
+
+// Queue runs submitted tasks with a fixed amount of workers, tasks waiting for a worker are limited to the queue size
+type Queue struct {
+	pending chan queued
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	closed bool
+	// ttl and maxKept limit the finished jobs kept, onRemove is called for every removed job
+	ttl      time.Duration
+	maxKept  int
+	onRemove func(id string)
+	// expiring is set once the goroutine removing expired jobs is started, retention wakes it up when
+	// the ttl changes, a single pending wake up is enough
+	expiring  bool
+	retention chan struct{}
+}
+
+type queued struct {
+	id   string
+	task Task
+}
+
+// NewQueue creates a queue with room for size waiting tasks and starts the workers
+func NewQueue(size, workers int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		pending:   make(chan queued, size),
+		ctx:       ctx,
+		cancel:    cancel,
+		jobs:      map[string]*Job{},
+		retention: make(chan struct{}, 1),
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Submit queues a task, ErrQueueFull is returned if there is no room for it
+func (q *Queue) Submit(task Task) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return Job{}, ErrClosed
+	}
+	select {
+	case q.pending <- queued{id: id, task: task}:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	job := &Job{ID: id, Status: StatusQueued, Created: time.Now()}
+	q.jobs[id] = job
+	return *job, nil
+}
+
+// Get returns the current state of a job
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns all jobs ordered by creation time
+func (q *Queue) List() []Job {
+	q.mu.Lock()
+	out := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		out = append(out, *job)
+	}
+	q.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out
+}
+
+// SetRetention removes finished jobs once they finished longer than ttl ago and the oldest finished jobs
+// when there are more than maxKept, a zero value disables each limit
+func (q *Queue) SetRetention(ttl time.Duration, maxKept int) {
+	q.mu.Lock()
+	q.ttl = ttl
+	q.maxKept = maxKept
+	start := ttl > 0 && !q.closed && !q.expiring
+	if start {
+		q.expiring = true
+		q.wg.Add(1)
+	}
+	q.mu.Unlock()
+	if start {
+		go q.expire()
+	}
+	select {
+	case q.retention <- struct{}{}:
+	default:
+	}
+	q.prune()
+}
+
+// OnRemove sets a function called with the id of every removed job, e.g. to delete its files
+func (q *Queue) OnRemove(fn func(id string)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.onRemove = fn
+}
+
+// Remove deletes a finished job, ErrActive is returned for queued and running jobs
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return ErrNotFound
+	}
+	if job.Finished == nil {
+		q.mu.Unlock()
+		return ErrActive
+	}
+	delete(q.jobs, id)
+	fn := q.onRemove
+	q.mu.Unlock()
+	if fn != nil {
+		fn(id)
+	}
+	return nil
+}
+
+// expire prunes the jobs until the queue is closed, it checks at least every minute or every ttl if shorter
+func (q *Queue) expire() {
+	defer q.wg.Done()
+	for {
+		q.mu.Lock()
+		every := time.Minute
+		if q.ttl > 0 {
+			every = min(q.ttl, every)
+		}
+		q.mu.Unlock()
+		timer := time.NewTimer(every)
+		select {
+		case <-q.ctx.Done():
+			timer.Stop()
+			return
+		case <-q.retention:
+			timer.Stop()
+		case <-timer.C:
+			q.prune()
+		}
+	}
+}
+
+// prune removes the finished jobs exceeding the retention
+func (q *Queue) prune() {
+	q.mu.Lock()
+	finished := []*Job{}
+	for _, job := range q.jobs {
+		if job.Finished != nil {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Finished.After(*finished[j].Finished)
+	})
+	removed := []string{}
+	for i, job := range finished {
+		expired := q.ttl > 0 && time.Since(*job.Finished) > q.ttl
+		if expired || (q.maxKept > 0 && i >= q.maxKept) {
+			delete(q.jobs, job.ID)
+			removed = append(removed, job.ID)
+		}
+	}
+	fn := q.onRemove
+	q.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	for _, id := range removed {
+		fn(id)
+	}
+}
+
+// Close stops accepting jobs, cancels the running ones and waits for the workers to finish,
+// queued jobs that did not start are marked as failed
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.pending)
+	q.mu.Unlock()
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for item := range q.pending {
+		if q.ctx.Err() != nil {
+			q.finish(item.id, q.ctx.Err())
+			continue
+		}
+		q.update(item.id, func(job *Job) {
+			now := time.Now()
+			job.Status = StatusRunning
+			job.Started = &now
+		})
+		err := q.run(item)
+		q.finish(item.id, err)
+		q.prune()
+	}
+}
+
+// run executes a task, a panic is reported as an error of the job instead of stopping the server
+func (q *Queue) run(item queued) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return item.task(q.ctx, func(done, total int, remaining time.Duration) {
+		q.update(item.id, func(job *Job) {
+			job.Done = done
+			job.Total = total
+			job.RemainingMs = remaining.Milliseconds()
+		})
+	})
+}
+
+func (q *Queue) finish(id string, err error) {
+	q.update(id, func(job *Job) {
+		now := time.Now()
+		job.Finished = &now
+		job.RemainingMs = 0
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = StatusDone
+	})
+}
+
+func (q *Queue) update(id string, fn func(*Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job, ok := q.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("unable to create job id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls the job until it has the status or the test times out
+func waitFor(t *testing.T, q *Queue, id string, status Status) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := q.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.Status == status {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	job, _ := q.Get(id)
+	t.Fatalf("job %s has status %s, expected %s", id, job.Status, status)
+	return job
+}
+
+func TestQueue(t *testing.T) {
+	q := NewQueue(1, 1)
+	defer q.Close()
+
+	release := make(chan struct{})
+	first, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error {
+		progress(1, 2, time.Second)
+		<-release
+		progress(2, 2, 0)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	running := waitFor(t, q, first.ID, StatusRunning)
+	for running.Done != 1 {
+		running, _ = q.Get(first.ID)
+	}
+	if running.Total != 2 || running.RemainingMs != 1000 {
+		t.Errorf("unexpected progress: %+v", running)
+	}
+
+	second, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error {
+		return errors.New("model not found")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the worker is busy and the queue holds a single waiting job
+	_, err = q.Submit(func(ctx context.Context, progress ProgressFunc) error { return nil })
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	close(release)
+	done := waitFor(t, q, first.ID, StatusDone)
+	if done.Done != 2 || done.Finished == nil {
+		t.Errorf("unexpected finished job: %+v", done)
+	}
+	failed := waitFor(t, q, second.ID, StatusFailed)
+	if failed.Error != "model not found" {
+		t.Errorf("unexpected error: %q", failed.Error)
+	}
+	if jobs := q.List(); len(jobs) != 2 || jobs[0].ID != first.ID {
+		t.Errorf("unexpected list: %+v", jobs)
+	}
+}
+
+func TestQueuePanic(t *testing.T) {
+	q := NewQueue(1, 1)
+	defer q.Close()
+	job, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := waitFor(t, q, job.ID, StatusFailed)
+	if failed.Error != "job panicked: boom" {
+		t.Errorf("unexpected error: %q", failed.Error)
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	q := NewQueue(1, 1)
+	started := make(chan struct{})
+	job, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	q.Close()
+	if got, _ := q.Get(job.ID); got.Status != StatusFailed {
+		t.Errorf("expected the running job to be cancelled, got %s", got.Status)
+	}
+	if _, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestQueueRetention(t *testing.T) {
+	q := NewQueue(5, 1)
+	defer q.Close()
+	removed := make(chan string, 5)
+	q.OnRemove(func(id string) { removed <- id })
+	q.SetRetention(0, 2)
+
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		job, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error { return nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, q, job.ID, StatusDone)
+		ids = append(ids, job.ID)
+	}
+	// the oldest finished job is removed once more than two are kept
+	if id := <-removed; id != ids[0] {
+		t.Errorf("expected %s to be removed, got %s", ids[0], id)
+	}
+	if _, ok := q.Get(ids[0]); ok {
+		t.Errorf("expected the oldest job to be removed")
+	}
+
+	if err := q.Remove("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	running, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Remove(running.ID); !errors.Is(err, ErrActive) {
+		t.Errorf("expected ErrActive, got %v", err)
+	}
+	if err := q.Remove(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-removed; id != ids[1] {
+		t.Errorf("expected %s to be removed, got %s", ids[1], id)
+	}
+}
+
+func TestQueueExpire(t *testing.T) {
+	q := NewQueue(1, 1)
+	defer q.Close()
+	removed := make(chan string, 1)
+	q.OnRemove(func(id string) { removed <- id })
+	// setting the retention again updates it without starting another expirer
+	q.SetRetention(time.Hour, 0)
+	q.SetRetention(20*time.Millisecond, 0)
+	q.mu.Lock()
+	if !q.expiring {
+		t.Errorf("expected the expirer to be started")
+	}
+	q.mu.Unlock()
+
+	job, err := q.Submit(func(ctx context.Context, progress ProgressFunc) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-removed:
+		if id != job.ID {
+			t.Errorf("unexpected removed job %s", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the finished job did not expire")
+	}
+}
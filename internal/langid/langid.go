@@ -0,0 +1,220 @@
+package langid
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/andresbott/substrans/internal/langtag"
+)
+
+// minLetters is the minimum amount of letters needed to attempt a detection
+const minLetters = 8
+
+// Code returns the language code of a language description like "spanish from spain", "es" or "es-ES".
+// The second return value is false if the language is unknown.
+func Code(lang string) (string, bool) {
+	tag, err := langtag.Parse(lang)
+	if err != nil {
+		return "", false
+	}
+	return tag.Language, true
+}
+
+// Supported tells if the language code can be detected
+func Supported(code string) bool {
+	_, ok := samples[code]
+	return ok
+}
+
+// SameLanguage compares two language descriptions, same is true if both refer to the same language
+// and regional is true if both set a script or region and they differ, e.g. "es-ES" and "es-MX"
+func SameLanguage(a, b string) (same, regional bool) {
+	tagA, errA := langtag.Parse(a)
+	tagB, errB := langtag.Parse(b)
+	if errA != nil || errB != nil || tagA.Language != tagB.Language {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)), false
+	}
+	qa := strings.TrimPrefix(tagA.String(), tagA.Language)
+	qb := strings.TrimPrefix(tagB.String(), tagB.Language)
+	return true, qa != "" && qb != "" && qa != qb
+}
+
+type profile struct {
+	counts map[string]int
+	total  int
+}
+
+var (
+	profiles     map[string]profile
+	vocabulary   int
+	profilesOnce sync.Once
+)
+
+func loadProfiles() {
+	profiles = map[string]profile{}
+	vocab := map[string]bool{}
+	for code, text := range samples {
+		p := profile{counts: map[string]int{}}
+		for _, g := range grams(text) {
+			p.counts[g]++
+			p.total++
+			vocab[g] = true
+		}
+		profiles[code] = p
+	}
+	vocabulary = len(vocab)
+}
+
+// grams returns the character 1 to 3-grams of every word of the text, words are padded with spaces
+// so that prefixes and suffixes get their own n-grams
+func grams(text string) []string {
+	out := []string{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' }) {
+		runes := []rune(" " + w + " ")
+		for n := 1; n <= 3; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				g := string(runes[i : i+n])
+				if g != " " {
+					out = append(out, g)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Detect returns the most likely language code of text and the confidence of the guess between 0 and 1.
+// An empty code is returned if the text is too short to decide.
+func Detect(text string) (string, float64) {
+	letters := 0
+	scripts := map[string]int{}
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		scripts[script(r)]++
+	}
+	if letters == 0 {
+		return "", 0
+	}
+
+	// kana is only used in japanese, that also uses han characters
+	if scripts["japanese"] > 0 {
+		return "ja", 1
+	}
+	main, count := "", 0
+	for s, c := range scripts {
+		if c > count {
+			main, count = s, c
+		}
+	}
+	switch main {
+	case "latin":
+		// handled below
+	case "other":
+		return "", 0
+	case "cyrillic":
+		if strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			return "uk", 1
+		}
+		return "ru", 1
+	default:
+		return main, 1
+	}
+
+	if letters < minLetters {
+		return "", 0
+	}
+	profilesOnce.Do(loadProfiles)
+
+	textGrams := grams(text)
+	scores := map[string]float64{}
+	best, bestScore := "", math.Inf(-1)
+	for code, p := range profiles {
+		score := 0.0
+		for _, g := range textGrams {
+			score += math.Log(float64(p.counts[g]+1) / float64(p.total+vocabulary))
+		}
+		scores[code] = score
+		if score > bestScore {
+			best, bestScore = code, score
+		}
+	}
+
+	// posterior probability of the best language assuming all languages are equally likely
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - bestScore)
+	}
+	return best, 1 / sum
+}
+
+// script returns a script name, or the language code for scripts used by a single supported language
+func script(r rune) string {
+	switch {
+	case unicode.Is(unicode.Latin, r):
+		return "latin"
+	case unicode.Is(unicode.Cyrillic, r):
+		return "cyrillic"
+	case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return "japanese"
+	case unicode.Is(unicode.Han, r):
+		return "zh"
+	case unicode.Is(unicode.Hangul, r):
+		return "ko"
+	case unicode.Is(unicode.Greek, r):
+		return "el"
+	case unicode.Is(unicode.Arabic, r):
+		return "ar"
+	case unicode.Is(unicode.Hebrew, r):
+		return "he"
+	case unicode.Is(unicode.Devanagari, r):
+		return "hi"
+	case unicode.Is(unicode.Thai, r):
+		return "th"
+	}
+	return "other"
+}
+
+// Untranslatable returns true if the text is not expected to change when translated, e.g. it only
+// contains numbers and punctuation, or it is a short sequence of names like "Sergeant Baraja".
+func Untranslatable(text string) bool {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if len(words) == 0 {
+		return true
+	}
+	if len(words) > 3 {
+		return false
+	}
+	for _, w := range words {
+		r := []rune(w)[0]
+		if !unicode.IsUpper(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// minConfidence is the detection confidence needed to report a translation in the wrong language
+const minConfidence = 0.9
+
+// Check verifies that translation of source is written in the target language code, it returns
+// an error describing the problem if the source was returned untouched or the translation is
+// confidently detected as another language. Untranslatable lines are always accepted.
+func Check(source, translation, target string) error {
+	if Untranslatable(source) || Untranslatable(translation) {
+		return nil
+	}
+	if strings.TrimSpace(source) == strings.TrimSpace(translation) {
+		return fmt.Errorf("translation is identical to the source")
+	}
+	got, confidence := Detect(translation)
+	if got != "" && got != target && confidence >= minConfidence {
+		return fmt.Errorf("translation detected as %s instead of %s", got, target)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package langid
+
+import "testing"
+
+func TestCode(t *testing.T) {
+	tcs := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{in: "spanish from spain", want: "es", ok: true},
+		{in: "es-ES", want: "es", ok: true},
+		{in: "pt_BR", want: "pt", ok: true},
+		{in: "German", want: "de", ok: true},
+		{in: "klingon", want: "", ok: false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.in, func(t *testing.T) {
+			got, ok := Code(tc.in)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("Code(%q) = %q, %v, want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	if !Supported("es") || Supported("tr") {
+		t.Errorf("expected es to be supported and tr not")
+	}
+}
+
+func TestSameLanguage(t *testing.T) {
+	tcs := []struct {
+		a, b     string
+		same     bool
+		regional bool
+	}{
+		{a: "english", b: "en", same: true},
+		{a: "spanish", b: "german"},
+		{a: "es-ES", b: "es-MX", same: true, regional: true},
+		{a: "spanish from spain", b: "es-AR", same: true, regional: true},
+		{a: "es-ES", b: "spanish", same: true},
+		{a: "klingon", b: "Klingon", same: true},
+	}
+	for _, tc := range tcs {
+		same, regional := SameLanguage(tc.a, tc.b)
+		if same != tc.same || regional != tc.regional {
+			t.Errorf("SameLanguage(%q, %q) = %v, %v, want %v, %v", tc.a, tc.b, same, regional, tc.same, tc.regional)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tcs := []struct {
+		text string
+		want string
+	}{
+		{text: "My thoughts were elsewhere.", want: "en"},
+		{text: "But there are no changes to report today.", want: "en"},
+		{text: "Mis pensamientos estaban en otro lugar.", want: "es"},
+		{text: "Esta muralla garantiza la paz de todos los que viven tras ella.", want: "es"},
+		{text: "Mes pensées étaient ailleurs.", want: "fr"},
+		{text: "Meine Gedanken waren woanders.", want: "de"},
+		{text: "I miei pensieri erano altrove.", want: "it"},
+		{text: "Os meus pensamentos estavam noutro lado.", want: "pt"},
+		{text: "Mijn gedachten waren ergens anders.", want: "nl"},
+		{text: "Мои мысли были где-то далеко.", want: "ru"},
+		{text: "私の考えは別のところにあった。", want: "ja"},
+		{text: "Ok", want: ""},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.text, func(t *testing.T) {
+			got, _ := Detect(tc.text)
+			if got != tc.want {
+				t.Errorf("Detect(%q) = %q, want %q", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUntranslatable(t *testing.T) {
+	tcs := []struct {
+		text string
+		want bool
+	}{
+		{text: "Sergeant Baraja", want: true},
+		{text: "1024", want: true},
+		{text: "...!", want: true},
+		{text: "Where's your report?", want: false},
+		{text: "Good then.", want: false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.text, func(t *testing.T) {
+			if got := Untranslatable(tc.text); got != tc.want {
+				t.Errorf("Untranslatable(%q) = %v, want %v", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tcs := []struct {
+		name        string
+		source      string
+		translation string
+		wantErr     bool
+	}{
+		{name: "correct translation", source: "My thoughts were elsewhere.", translation: "Mis pensamientos estaban en otro lugar.", wantErr: false},
+		{name: "untouched line", source: "My thoughts were elsewhere.", translation: "My thoughts were elsewhere.", wantErr: true},
+		{name: "wrong language", source: "My thoughts were elsewhere.", translation: "Mes pensées étaient ailleurs, sergent.", wantErr: true},
+		{name: "names are kept", source: "Sergeant Baraja", translation: "Sergeant Baraja", wantErr: false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Check(tc.source, tc.translation, "es")
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package langid
+
+// samples contains a small text per language used to build the n-gram profiles. The texts favour
+// conversational sentences and frequent words, since subtitles are mostly short dialogue lines.
+var samples = map[string]string{
+	"en": `Where is your report? My apologies, sergeant. My thoughts were elsewhere, but there are no changes to report today.
+I wish I could see as clearly in the dark as you can. I would love to join you on the night watch someday, sir.
+What are you doing here? I have been waiting for you all night. Do not worry, everything will be fine.
+We have to go now, they are coming for us. This is not what I wanted, and you know it.
+Thank you very much for your help. I think that we should leave before it is too late.
+Can you hear me? Of course I can, I am right here with you. Why did you not tell me the truth?
+It was the best day of my life and I will never forget it. They said that the king would arrive tomorrow.
+Let's find out, shall we? Now allow me to offer you a gift in return. The wall guarantees the peace of every person who lives behind it.
+Please come with me, there is something that I want to show you. How long have you been working with them?
+I don't know what happened, but we need to find out who did this. She was the one who saved us all.`,
+	"es": `¿Dónde está tu informe? Mis disculpas, sargento. Mis pensamientos estaban en otra parte, pero hoy no hay cambios que informar.
+Ojalá pudiera ver tan claro en la oscuridad como tú. Me encantaría acompañarte en la guardia nocturna algún día, señor.
+¿Qué estás haciendo aquí? Te he estado esperando toda la noche. No te preocupes, todo va a salir bien.
+Tenemos que irnos ya, vienen a por nosotros. Esto no es lo que yo quería, y tú lo sabes.
+Muchas gracias por tu ayuda. Creo que deberíamos marcharnos antes de que sea demasiado tarde.
+¿Me oyes? Claro que sí, estoy aquí contigo. ¿Por qué no me dijiste la verdad?
+Fue el mejor día de mi vida y nunca lo olvidaré. Dijeron que el rey llegaría mañana.
+Vamos a averiguarlo, ¿de acuerdo? Ahora permíteme ofrecerte un regalo a cambio. La muralla garantiza la paz de todas las personas que viven detrás de ella.
+Por favor, ven conmigo, hay algo que quiero enseñarte. ¿Cuánto tiempo llevas trabajando con ellos?
+No sé qué ha pasado, pero tenemos que descubrir quién lo hizo. Ella fue la que nos salvó a todos.`,
+	"fr": `Où est ton rapport ? Mes excuses, sergent. Mes pensées étaient ailleurs, mais il n'y a rien de nouveau à signaler aujourd'hui.
+J'aimerais voir aussi clair que toi dans le noir. J'aimerais beaucoup me joindre à vous pour la garde de nuit un jour, monsieur.
+Qu'est-ce que tu fais ici ? Je t'ai attendu toute la nuit. Ne t'inquiète pas, tout va bien se passer.
+Nous devons partir maintenant, ils viennent nous chercher. Ce n'est pas ce que je voulais, et tu le sais.
+Merci beaucoup pour ton aide. Je pense que nous devrions partir avant qu'il ne soit trop tard.
+Tu m'entends ? Bien sûr, je suis juste là avec toi. Pourquoi ne m'as-tu pas dit la vérité ?
+C'était le plus beau jour de ma vie et je ne l'oublierai jamais. Ils ont dit que le roi arriverait demain.
+Voyons voir, d'accord ? Maintenant, permets-moi de t'offrir un cadeau en retour. Ce mur garantit la paix de chaque personne qui vit derrière lui.
+S'il te plaît, viens avec moi, il y a quelque chose que je veux te montrer. Depuis combien de temps travailles-tu avec eux ?
+Je ne sais pas ce qui s'est passé, mais nous devons découvrir qui a fait ça. C'est elle qui nous a tous sauvés.`,
+	"de": `Wo ist dein Bericht? Entschuldigung, Feldwebel. Ich war mit meinen Gedanken woanders, aber heute gibt es nichts Neues zu berichten.
+Ich wünschte, ich könnte im Dunkeln so klar sehen wie du. Ich würde mich gerne eines Tages der Nachtwache anschließen, mein Herr.
+Was machst du hier? Ich habe die ganze Nacht auf dich gewartet. Mach dir keine Sorgen, alles wird gut.
+Wir müssen jetzt gehen, sie kommen, um uns zu holen. Das ist nicht, was ich wollte, und das weißt du.
+Vielen Dank für deine Hilfe. Ich glaube, wir sollten gehen, bevor es zu spät ist.
+Kannst du mich hören? Natürlich, ich bin doch hier bei dir. Warum hast du mir nicht die Wahrheit gesagt?
+Es war der schönste Tag meines Lebens und ich werde ihn nie vergessen. Sie sagten, dass der König morgen ankommen würde.
+Finden wir es heraus, ja? Jetzt erlaube mir, dir im Gegenzug ein Geschenk zu machen. Diese Mauer garantiert den Frieden jedes einzelnen Menschen, der dahinter lebt.
+Bitte komm mit mir, ich möchte dir etwas zeigen. Wie lange arbeitest du schon mit ihnen zusammen?
+Ich weiß nicht, was passiert ist, aber wir müssen herausfinden, wer das getan hat. Sie war diejenige, die uns alle gerettet hat.`,
+	"it": `Dov'è il tuo rapporto? Le mie scuse, sergente. I miei pensieri erano altrove, ma oggi non ci sono cambiamenti da segnalare.
+Vorrei poter vedere al buio chiaramente come te. Mi piacerebbe unirmi a te nella guardia notturna un giorno, signore.
+Che cosa ci fai qui? Ti ho aspettato tutta la notte. Non ti preoccupare, andrà tutto bene.
+Dobbiamo andare adesso, stanno venendo a prenderci. Questo non è quello che volevo, e tu lo sai.
+Grazie mille per il tuo aiuto. Penso che dovremmo andarcene prima che sia troppo tardi.
+Mi senti? Certo che sì, sono proprio qui con te. Perché non mi hai detto la verità?
+È stato il giorno più bello della mia vita e non lo dimenticherò mai. Hanno detto che il re sarebbe arrivato domani.
+Scopriamolo, va bene? Ora permettimi di offrirti un regalo in cambio. Questo muro garantisce la pace di ogni persona che vive dietro di esso.
+Per favore, vieni con me, c'è una cosa che voglio mostrarti. Da quanto tempo lavori con loro?
+Non so cosa sia successo, ma dobbiamo scoprire chi è stato. È stata lei a salvarci tutti.`,
+	"pt": `Onde está o teu relatório? As minhas desculpas, sargento. Os meus pensamentos estavam noutro lugar, mas hoje não há mudanças a relatar.
+Quem me dera conseguir ver tão bem no escuro como tu. Adoraria juntar-me a ti na vigia noturna um dia, senhor.
+O que estás a fazer aqui? Estive à tua espera a noite toda. Não te preocupes, vai correr tudo bem.
+Temos de ir agora, eles estão a vir buscar-nos. Isto não é o que eu queria, e tu sabes disso.
+Muito obrigado pela tua ajuda. Acho que devíamos ir embora antes que seja tarde demais.
+Consegues ouvir-me? Claro que sim, estou aqui contigo. Por que não me disseste a verdade?
+Foi o melhor dia da minha vida e nunca o vou esquecer. Disseram que o rei chegaria amanhã.
+Vamos descobrir, está bem? Agora permite-me oferecer-te um presente em troca. Esta muralha garante a paz de todas as pessoas que vivem atrás dela.
+Por favor, vem comigo, há uma coisa que te quero mostrar. Há quanto tempo trabalhas com eles?
+Não sei o que aconteceu, mas temos de descobrir quem fez isto. Foi ela que nos salvou a todos.`,
+	"nl": `Waar is je verslag? Mijn excuses, sergeant. Mijn gedachten waren ergens anders, maar er zijn vandaag geen veranderingen te melden.
+Ik wou dat ik in het donker zo goed kon zien als jij. Ik zou graag ooit met je meegaan op de nachtwacht, meneer.
+Wat doe je hier? Ik heb de hele nacht op je gewacht. Maak je geen zorgen, alles komt goed.
+We moeten nu gaan, ze komen ons halen. Dit is niet wat ik wilde, en dat weet je.
+Heel erg bedankt voor je hulp. Ik denk dat we moeten vertrekken voordat het te laat is.
+Kun je me horen? Natuurlijk, ik ben hier bij je. Waarom heb je me de waarheid niet verteld?
+Het was de mooiste dag van mijn leven en ik zal het nooit vergeten. Ze zeiden dat de koning morgen zou aankomen.
+Laten we het uitzoeken, goed? Sta me nu toe je in ruil een geschenk aan te bieden. Deze muur garandeert de vrede van iedereen die erachter woont.
+Kom alsjeblieft met me mee, ik wil je iets laten zien. Hoe lang werk je al met hen samen?
+Ik weet niet wat er gebeurd is, maar we moeten uitzoeken wie dit gedaan heeft. Zij was degene die ons allemaal heeft gered.`,
+	"ca": `On és el teu informe? Les meves disculpes, sergent. Els meus pensaments eren en un altre lloc, però avui no hi ha canvis per informar.
+Tant de bo pogués veure tan clar a la foscor com tu. M'agradaria acompanyar-te a la guàrdia nocturna algun dia, senyor.
+Què fas aquí? T'he estat esperant tota la nit. No et preocupis, tot anirà bé.
+Hem de marxar ara, vénen a buscar-nos. Això no és el que jo volia, i tu ho saps.
+Moltes gràcies per la teva ajuda. Crec que hauríem de marxar abans que sigui massa tard.
+Em sents? És clar que sí, sóc aquí amb tu. Per què no em vas dir la veritat?
+Va ser el millor dia de la meva vida i no l'oblidaré mai. Van dir que el rei arribaria demà.
+Anem a descobrir-ho, d'acord? Ara permet-me oferir-te un regal a canvi. Aquesta muralla garanteix la pau de totes les persones que hi viuen darrere.
+Si us plau, vine amb mi, hi ha una cosa que et vull ensenyar. Quant de temps fa que treballes amb ells?
+No sé què ha passat, però hem de descobrir qui ho ha fet. Ella va ser qui ens va salvar a tots.`,
+	"pl": `Gdzie jest twój raport? Przepraszam, sierżancie. Myślami byłem gdzie indziej, ale dzisiaj nie ma żadnych zmian do zgłoszenia.
+Chciałbym widzieć w ciemności tak wyraźnie jak ty. Bardzo chciałbym kiedyś dołączyć do ciebie na nocnej warcie, panie.
+Co ty tutaj robisz? Czekałem na ciebie całą noc. Nie martw się, wszystko będzie dobrze.
+Musimy już iść, oni po nas idą. To nie jest to, czego chciałem, i dobrze o tym wiesz.
+Bardzo dziękuję za twoją pomoc. Myślę, że powinniśmy odejść, zanim będzie za późno.
+Słyszysz mnie? Oczywiście, jestem tutaj z tobą. Dlaczego nie powiedziałeś mi prawdy?
+To był najlepszy dzień w moim życiu i nigdy go nie zapomnę. Powiedzieli, że król przybędzie jutro.
+Przekonajmy się, dobrze? Teraz pozwól mi dać ci prezent w zamian. Ten mur gwarantuje pokój każdej osobie, która za nim mieszka.
+Proszę, chodź ze mną, chcę ci coś pokazać. Jak długo z nimi pracujesz?
+Nie wiem, co się stało, ale musimy się dowiedzieć, kto to zrobił. To ona nas wszystkich uratowała.`,
+	"sv": `Var är din rapport? Jag ber om ursäkt, sergeant. Mina tankar var någon annanstans, men det finns inga förändringar att rapportera i dag.
+Jag önskar att jag kunde se lika tydligt i mörkret som du. Jag skulle gärna följa med dig på nattvakten någon dag, herre.
+Vad gör du här? Jag har väntat på dig hela natten. Oroa dig inte, allt kommer att bli bra.
+Vi måste gå nu, de kommer för att hämta oss. Det här är inte vad jag ville, och det vet du.
+Tack så mycket för din hjälp. Jag tycker att vi borde gå innan det är för sent.
+Hör du mig? Självklart, jag är ju här med dig. Varför berättade du inte sanningen för mig?
+Det var den bästa dagen i mitt liv och jag kommer aldrig att glömma den. De sa att kungen skulle komma i morgon.
+Låt oss ta reda på det, eller hur? Tillåt mig nu att ge dig en gåva i gengäld. Den här muren garanterar freden för varje människa som bor bakom den.
+Snälla, följ med mig, det finns något jag vill visa dig. Hur länge har du arbetat med dem?
+Jag vet inte vad som hände, men vi måste ta reda på vem som gjorde det. Det var hon som räddade oss alla.`,
+}
@@ -0,0 +1,171 @@
+package langtag
+
+// languages maps ISO 639-1 codes to english names
+var languages = map[string]string{
+	"af":  "Afrikaans",
+	"ar":  "Arabic",
+	"bg":  "Bulgarian",
+	"bn":  "Bengali",
+	"ca":  "Catalan",
+	"cs":  "Czech",
+	"cy":  "Welsh",
+	"da":  "Danish",
+	"de":  "German",
+	"el":  "Greek",
+	"en":  "English",
+	"es":  "Spanish",
+	"et":  "Estonian",
+	"eu":  "Basque",
+	"fa":  "Persian",
+	"fi":  "Finnish",
+	"fil": "Filipino",
+	"fr":  "French",
+	"ga":  "Irish",
+	"gl":  "Galician",
+	"he":  "Hebrew",
+	"hi":  "Hindi",
+	"hr":  "Croatian",
+	"hu":  "Hungarian",
+	"id":  "Indonesian",
+	"is":  "Icelandic",
+	"it":  "Italian",
+	"ja":  "Japanese",
+	"ko":  "Korean",
+	"lt":  "Lithuanian",
+	"lv":  "Latvian",
+	"ms":  "Malay",
+	"nb":  "Norwegian Bokmål",
+	"nl":  "Dutch",
+	"nn":  "Norwegian Nynorsk",
+	"no":  "Norwegian",
+	"pl":  "Polish",
+	"pt":  "Portuguese",
+	"ro":  "Romanian",
+	"ru":  "Russian",
+	"sk":  "Slovak",
+	"sl":  "Slovenian",
+	"sr":  "Serbian",
+	"sv":  "Swedish",
+	"sw":  "Swahili",
+	"ta":  "Tamil",
+	"th":  "Thai",
+	"tr":  "Turkish",
+	"uk":  "Ukrainian",
+	"ur":  "Urdu",
+	"vi":  "Vietnamese",
+	"zh":  "Chinese",
+}
+
+// scripts maps ISO 15924 codes to the way they are described
+var scripts = map[string]string{
+	"Arab": "Arabic script",
+	"Cyrl": "Cyrillic",
+	"Hans": "Simplified",
+	"Hant": "Traditional",
+	"Latn": "Latin",
+}
+
+// regions maps ISO 3166-1 and UN M49 codes to english names
+var regions = map[string]string{
+	"419": "Latin America",
+	"AR":  "Argentina",
+	"AT":  "Austria",
+	"AU":  "Australia",
+	"BE":  "Belgium",
+	"BR":  "Brazil",
+	"CA":  "Canada",
+	"CH":  "Switzerland",
+	"CL":  "Chile",
+	"CN":  "China",
+	"CO":  "Colombia",
+	"DE":  "Germany",
+	"ES":  "Spain",
+	"FR":  "France",
+	"GB":  "United Kingdom",
+	"HK":  "Hong Kong",
+	"IE":  "Ireland",
+	"IN":  "India",
+	"IT":  "Italy",
+	"JP":  "Japan",
+	"KR":  "South Korea",
+	"MX":  "Mexico",
+	"NL":  "Netherlands",
+	"NZ":  "New Zealand",
+	"PE":  "Peru",
+	"PT":  "Portugal",
+	"SG":  "Singapore",
+	"TW":  "Taiwan",
+	"US":  "United States",
+	"VE":  "Venezuela",
+	"ZA":  "South Africa",
+}
+
+// alpha3 maps the ISO 639-2 codes used in file names, e.g. by media servers, to ISO 639-1 codes;
+// both the bibliographic and terminology codes are listed where they differ
+var alpha3 = map[string]string{
+	"afr": "af",
+	"ara": "ar",
+	"bul": "bg",
+	"ben": "bn",
+	"cat": "ca",
+	"ces": "cs",
+	"cze": "cs",
+	"cym": "cy",
+	"wel": "cy",
+	"dan": "da",
+	"deu": "de",
+	"ger": "de",
+	"ell": "el",
+	"gre": "el",
+	"eng": "en",
+	"spa": "es",
+	"est": "et",
+	"eus": "eu",
+	"baq": "eu",
+	"fas": "fa",
+	"per": "fa",
+	"fin": "fi",
+	"fil": "fil",
+	"fra": "fr",
+	"fre": "fr",
+	"gle": "ga",
+	"glg": "gl",
+	"heb": "he",
+	"hin": "hi",
+	"hrv": "hr",
+	"hun": "hu",
+	"ind": "id",
+	"isl": "is",
+	"ice": "is",
+	"ita": "it",
+	"jpn": "ja",
+	"kor": "ko",
+	"lit": "lt",
+	"lav": "lv",
+	"msa": "ms",
+	"may": "ms",
+	"nob": "nb",
+	"nld": "nl",
+	"dut": "nl",
+	"nno": "nn",
+	"nor": "no",
+	"pol": "pl",
+	"por": "pt",
+	"ron": "ro",
+	"rum": "ro",
+	"rus": "ru",
+	"slk": "sk",
+	"slo": "sk",
+	"slv": "sl",
+	"srp": "sr",
+	"swe": "sv",
+	"swa": "sw",
+	"tam": "ta",
+	"tha": "th",
+	"tur": "tr",
+	"ukr": "uk",
+	"urd": "ur",
+	"vie": "vi",
+	"zho": "zh",
+	"chi": "zh",
+}
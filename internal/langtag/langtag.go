@@ -0,0 +1,268 @@
+// Package langtag parses BCP-47 language tags like "es-ES", "pt-BR" or "zh-Hant" and describes them in english
+// so that they can be used in prompts
+package langtag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tag is a parsed language tag, only the language, script and region subtags are supported
+type Tag struct {
+	Language string
+	Script   string
+	Region   string
+}
+
+// Parse reads a BCP-47 tag, "_" is accepted as separator and the case is normalized, e.g. "pt_br" is "pt-BR".
+// Language names like "spanish" are accepted as well, descriptions like "spanish from spain" or "Portuguese (Brazil)"
+// also set the script and region.
+// Unknown languages, scripts or regions return an error with suggestions.
+func Parse(s string) (Tag, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Tag{}, fmt.Errorf("empty language")
+	}
+
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '_' })
+	if isCode(parts) {
+		t := Tag{Language: strings.ToLower(parts[0])}
+		if _, ok := languages[t.Language]; !ok {
+			return Tag{}, unknown("language", s, t.Language, suggest(t.Language))
+		}
+		for _, p := range parts[1:] {
+			switch {
+			case len(p) == 4 && t.Script == "" && t.Region == "":
+				t.Script = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+				if _, ok := scripts[t.Script]; !ok {
+					return Tag{}, unknown("script", s, p, keys(scripts))
+				}
+			case (len(p) == 2 || len(p) == 3) && t.Region == "":
+				t.Region = strings.ToUpper(p)
+				if _, ok := regions[t.Region]; !ok {
+					return Tag{}, unknown("region", s, p, nil)
+				}
+			default:
+				return Tag{}, fmt.Errorf("unsupported language tag %q, use a code like es-ES, pt-BR or zh-Hant", s)
+			}
+		}
+		return t, nil
+	}
+
+	// free text, e.g. "Spanish", "spanish from spain" or "Portuguese (Brazil)"
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(words) > 0 {
+		for code, name := range languages {
+			if strings.ToLower(name) == words[0] {
+				return parseDetails(s, Tag{Language: code}, words[1:])
+			}
+		}
+	}
+	return Tag{}, unknown("language", s, s, suggest(s))
+}
+
+// fillers are the words ignored in the details of a free text language, e.g. "from" in "spanish from spain"
+var fillers = map[string]bool{"from": true, "in": true, "of": true, "as": true, "spoken": true, "the": true}
+
+// parseDetails reads the script and region following the language name of a free text language,
+// details that are not understood return an error instead of being dropped
+func parseDetails(input string, t Tag, words []string) (Tag, error) {
+	details := []string{}
+	for _, w := range words {
+		if !fillers[w] {
+			details = append(details, w)
+		}
+	}
+	if len(details) == 0 {
+		return t, nil
+	}
+	// the script comes first, e.g. "chinese traditional taiwan"
+	for i := 0; i <= len(details); i++ {
+		script := lookupFold(scripts, strings.Join(details[:i], " "))
+		region := lookupFold(regions, strings.Join(details[i:], " "))
+		if (i == 0 || script != "") && (i == len(details) || region != "") {
+			t.Script, t.Region = script, region
+			return t, nil
+		}
+	}
+	return Tag{}, fmt.Errorf("unknown region %q in %q, use a code like es-ES, pt-BR or zh-Hant", strings.Join(details, " "), input)
+}
+
+// ParseName reads a description returned by Name, e.g. "Portuguese (Brazil)", false is returned if it
+// is not the description of a known tag
+func ParseName(name string) (Tag, bool) {
+	langName, details, hasDetails := strings.Cut(strings.TrimSpace(name), " (")
+	t := Tag{}
+	for code, n := range languages {
+		if n == langName {
+			t.Language = code
+		}
+	}
+	if t.Language == "" {
+		return Tag{}, false
+	}
+	if !hasDetails {
+		return t, true
+	}
+	if !strings.HasSuffix(details, ")") {
+		return Tag{}, false
+	}
+	for _, d := range strings.Split(strings.TrimSuffix(details, ")"), ", ") {
+		switch {
+		case t.Script == "" && t.Region == "" && lookup(scripts, d) != "":
+			t.Script = lookup(scripts, d)
+		case t.Region == "" && lookup(regions, d) != "":
+			t.Region = lookup(regions, d)
+		default:
+			return Tag{}, false
+		}
+	}
+	return t, true
+}
+
+// lookup returns the key of the value in m
+func lookup(m map[string]string, value string) string {
+	for k, v := range m {
+		if v == value {
+			return k
+		}
+	}
+	return ""
+}
+
+// lookupFold returns the key of the value in m ignoring case, empty if value is empty or not found
+func lookupFold(m map[string]string, value string) string {
+	if value == "" {
+		return ""
+	}
+	for k, v := range m {
+		if strings.EqualFold(v, value) {
+			return k
+		}
+	}
+	return ""
+}
+
+// ParseAlpha3 reads a three letter ISO 639-2 code like "eng" or "ger", as found in the subtitle file names of media
+// servers, false is returned for unknown codes
+func ParseAlpha3(code string) (Tag, bool) {
+	lang, ok := alpha3[strings.ToLower(code)]
+	if !ok {
+		return Tag{}, false
+	}
+	return Tag{Language: lang}, true
+}
+
+// isCode returns true if the parts look like the subtags of a code instead of free text
+func isCode(parts []string) bool {
+	if len(parts) == 0 || len(parts[0]) < 2 || len(parts[0]) > 3 {
+		return false
+	}
+	for _, p := range parts {
+		for _, r := range p {
+			if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String returns the normalized tag, e.g. "pt-BR"
+func (t Tag) String() string {
+	out := []string{t.Language}
+	if t.Script != "" {
+		out = append(out, t.Script)
+	}
+	if t.Region != "" {
+		out = append(out, t.Region)
+	}
+	return strings.Join(out, "-")
+}
+
+// Name returns an english description of the tag, e.g. "Portuguese (Brazil)" or "Chinese (Traditional)"
+func (t Tag) Name() string {
+	name := languages[t.Language]
+	details := []string{}
+	if t.Script != "" {
+		details = append(details, scripts[t.Script])
+	}
+	if t.Region != "" {
+		details = append(details, regions[t.Region])
+	}
+	if len(details) > 0 {
+		name += " (" + strings.Join(details, ", ") + ")"
+	}
+	return name
+}
+
+func unknown(kind, input, value string, suggestions []string) error {
+	if len(suggestions) == 0 {
+		return fmt.Errorf("unknown %s %q in %q", kind, value, input)
+	}
+	return fmt.Errorf("unknown %s %q in %q, did you mean: %s", kind, value, input, strings.Join(suggestions, ", "))
+}
+
+// maxSuggestions is the amount of similar languages suggested for an unknown one
+const maxSuggestions = 3
+
+// suggest returns the language codes whose code or name are most similar to s
+func suggest(s string) []string {
+	s = strings.ToLower(s)
+	type candidate struct {
+		code string
+		dist int
+	}
+	candidates := []candidate{}
+	for code, name := range languages {
+		d := min(distance(s, code), distance(s, strings.ToLower(name)))
+		// only suggest reasonably close values, e.g. a typo or a wrong letter in a code
+		if d <= max(1, len(s)/3) {
+			candidates = append(candidates, candidate{code: code, dist: d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].code < candidates[j].code
+	})
+	out := []string{}
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		out = append(out, fmt.Sprintf("%s (%s)", candidates[i].code, languages[candidates[i].code]))
+	}
+	return out
+}
+
+// distance is the levenshtein distance between two strings
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
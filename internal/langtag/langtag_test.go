@@ -0,0 +1,81 @@
+package langtag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		in      string
+		code    string
+		name    string
+		wantErr string
+	}{
+		{in: "es-ES", code: "es-ES", name: "Spanish (Spain)"},
+		{in: "pt_br", code: "pt-BR", name: "Portuguese (Brazil)"},
+		{in: "zh-Hant", code: "zh-Hant", name: "Chinese (Traditional)"},
+		{in: "zh-hans-cn", code: "zh-Hans-CN", name: "Chinese (Simplified, China)"},
+		{in: "es-419", code: "es-419", name: "Spanish (Latin America)"},
+		{in: "DE", code: "de", name: "German"},
+		{in: "spanish", code: "es", name: "Spanish"},
+		{in: "spanish from spain", code: "es-ES", name: "Spanish (Spain)"},
+		{in: "Portuguese (Brazil)", code: "pt-BR", name: "Portuguese (Brazil)"},
+		{in: "chinese (traditional, taiwan)", code: "zh-Hant-TW", name: "Chinese (Traditional, Taiwan)"},
+		{in: "english from the united states", code: "en-US", name: "English (United States)"},
+		{in: "spanish from atlantis", wantErr: `unknown region "atlantis" in "spanish from atlantis", use a code like es-ES`},
+		{in: "eng", wantErr: `unknown language "eng" in "eng", did you mean: en (English)`},
+		{in: "spanich", wantErr: "did you mean: es (Spanish)"},
+		{in: "es-XX", wantErr: `unknown region "XX"`},
+		{in: "zh-Hanx", wantErr: `unknown script "Hanx"`},
+		{in: "klingon", wantErr: `unknown language "klingon"`},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := Parse(tc.in)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tc.code || got.Name() != tc.name {
+				t.Errorf("Parse(%q) = %q %q, want %q %q", tc.in, got.String(), got.Name(), tc.code, tc.name)
+			}
+		})
+	}
+}
+
+func TestParseAlpha3(t *testing.T) {
+	tcs := map[string]string{"eng": "en", "GER": "de", "deu": "de", "chi": "zh", "spa": "es"}
+	for in, want := range tcs {
+		got, ok := ParseAlpha3(in)
+		if !ok || got.String() != want {
+			t.Errorf("ParseAlpha3(%q) = %q %v, want %q", in, got.String(), ok, want)
+		}
+	}
+	if _, ok := ParseAlpha3("xyz"); ok {
+		t.Error("expected unknown code to be rejected")
+	}
+}
+
+func TestParseName(t *testing.T) {
+	for _, code := range []string{"es", "pt-BR", "zh-Hant", "zh-Hans-CN", "es-419"} {
+		tag, err := Parse(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := ParseName(tag.Name())
+		if !ok || got != tag {
+			t.Errorf("ParseName(%q) = %q %v, want %q", tag.Name(), got.String(), ok, code)
+		}
+	}
+	for _, name := range []string{"Klingon", "Spanish (Mars)", "Spanish (Spain"} {
+		if _, ok := ParseName(name); ok {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
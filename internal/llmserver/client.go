@@ -0,0 +1,187 @@
+// Package llmserver talks to the management api of the LLM servers, e.g. to list or download models
+package llmserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backends supported by the client, same values as the llmtranslate backends
+const (
+	BackendOllama = "ollama"
+	BackendOpenAI = "openai"
+)
+
+const (
+	defaultOllamaUrl = "http://127.0.0.1:11434"
+	defaultOpenAIUrl = "https://api.openai.com/v1"
+)
+
+// Client is a minimal client of the ollama and openai model apis
+type Client struct {
+	backend string
+	url     string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient creates a client for the backend, the default url of the backend is used if url is empty
+func NewClient(backend, url, apiKey string) (*Client, error) {
+	switch backend {
+	case BackendOllama, "":
+		backend = BackendOllama
+		if url == "" {
+			url = defaultOllamaUrl
+		}
+	case BackendOpenAI:
+		if url == "" {
+			url = defaultOpenAIUrl
+		}
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s", backend)
+	}
+	return &Client{
+		backend: backend,
+		url:     strings.TrimRight(url, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{},
+	}, nil
+}
+
+// Model is a model available on the server
+type Model struct {
+	Name string
+	// Size in bytes, 0 if the server does not report it
+	Size     int64
+	Modified time.Time
+}
+
+// List returns the models available on the server sorted by name
+func (c *Client) List(ctx context.Context) ([]Model, error) {
+	models := []Model{}
+	if c.backend == BackendOllama {
+		payload := struct {
+			Models []struct {
+				Name       string    `json:"name"`
+				Size       int64     `json:"size"`
+				ModifiedAt time.Time `json:"modified_at"`
+			} `json:"models"`
+		}{}
+		err := c.get(ctx, "/api/tags", &payload)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range payload.Models {
+			models = append(models, Model{Name: m.Name, Size: m.Size, Modified: m.ModifiedAt})
+		}
+	} else {
+		payload := struct {
+			Data []struct {
+				ID      string `json:"id"`
+				Created int64  `json:"created"`
+			} `json:"data"`
+		}{}
+		err := c.get(ctx, "/models", &payload)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range payload.Data {
+			models = append(models, Model{Name: m.ID, Modified: time.Unix(m.Created, 0)})
+		}
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models, nil
+}
+
+// Progress is reported while pulling a model
+type Progress struct {
+	Status string
+	// Total and Completed are the bytes of the layer being downloaded, both are 0 for other steps
+	Total     int64
+	Completed int64
+}
+
+// Pull downloads a model, progress is called for every update sent by the server, only supported by ollama
+func (c *Client) Pull(ctx context.Context, name string, progress func(Progress)) error {
+	if c.backend != BackendOllama {
+		return fmt.Errorf("pulling models is not supported by the %s backend", c.backend)
+	}
+	body, err := json.Marshal(map[string]any{"model": name, "stream": true})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	// the response is a stream of json objects, one per line
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		msg := struct {
+			Status    string `json:"status"`
+			Total     int64  `json:"total"`
+			Completed int64  `json:"completed"`
+			Error     string `json:"error"`
+		}{}
+		err = json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			return fmt.Errorf("unable to parse pull progress: %v", err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("unable to pull %s: %s", name, msg.Error)
+		}
+		if progress != nil {
+			progress(Progress{Status: msg.Status, Total: msg.Total, Completed: msg.Completed})
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *Client) get(ctx context.Context, path string, into any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	err = json.NewDecoder(resp.Body).Decode(into)
+	if err != nil {
+		return fmt.Errorf("unable to parse response of %s: %v", path, err)
+	}
+	return nil
+}
+
+func statusError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("server responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
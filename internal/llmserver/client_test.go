@@ -0,0 +1,123 @@
+package llmserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func stubServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models": [
+			{"name": "phi4:14b", "size": 9053116391, "modified_at": "2025-01-10T10:00:00Z"},
+			{"name": "llama3.1:8b", "size": 4920753328, "modified_at": "2025-01-09T10:00:00Z"}
+		]}`)
+	})
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error": "invalid api key"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"data": [{"id": "gpt-4o-mini", "created": 1721172741}]}`)
+	})
+	mux.HandleFunc("POST /api/pull", func(w http.ResponseWriter, r *http.Request) {
+		req := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] == "missing" {
+			fmt.Fprintln(w, `{"status": "pulling manifest"}`)
+			fmt.Fprintln(w, `{"error": "pull model manifest: file does not exist"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status": "pulling manifest"}`)
+		fmt.Fprintln(w, `{"status": "pulling 8eeb52dfb3bb", "total": 100, "completed": 50}`)
+		fmt.Fprintln(w, `{"status": "pulling 8eeb52dfb3bb", "total": 100, "completed": 100}`)
+		fmt.Fprintln(w, `{"status": "success"}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestList(t *testing.T) {
+	srv := stubServer(t)
+	ctx := context.Background()
+
+	c, err := NewClient(BackendOllama, srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := c.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, m := range models {
+		names = append(names, m.Name)
+	}
+	if diff := cmp.Diff([]string{"llama3.1:8b", "phi4:14b"}, names); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if models[0].Size != 4920753328 {
+		t.Errorf("unexpected size %d", models[0].Size)
+	}
+
+	c, err = NewClient(BackendOpenAI, srv.URL+"/v1", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err = c.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0].Name != "gpt-4o-mini" {
+		t.Errorf("unexpected models %v", models)
+	}
+
+	c, _ = NewClient(BackendOpenAI, srv.URL+"/v1", "wrong")
+	_, err = c.List(ctx)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an authorization error, got %v", err)
+	}
+}
+
+func TestPull(t *testing.T) {
+	srv := stubServer(t)
+	c, err := NewClient(BackendOllama, srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []Progress{}
+	err = c.Pull(context.Background(), "phi4:14b", func(p Progress) {
+		got = append(got, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Progress{
+		{Status: "pulling manifest"},
+		{Status: "pulling 8eeb52dfb3bb", Total: 100, Completed: 50},
+		{Status: "pulling 8eeb52dfb3bb", Total: 100, Completed: 100},
+		{Status: "success"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	err = c.Pull(context.Background(), "missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("expected a pull error, got %v", err)
+	}
+
+	c, _ = NewClient(BackendOpenAI, srv.URL, "")
+	if err := c.Pull(context.Background(), "gpt-4o", nil); err == nil {
+		t.Error("expected an error pulling from openai")
+	}
+}
@@ -0,0 +1,93 @@
+package llmtranslate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CacheEntry is a stored translation
+type CacheEntry struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Lang   string `json:"lang"`
+	// SourceLang is empty in entries stored by older versions
+	SourceLang string `json:"source_lang,omitempty"`
+	Model      string `json:"model"`
+	// Prompt is the hash of the prompt templates used for the translation
+	Prompt string `json:"prompt"`
+}
+
+// Cache stores translations in a json file so that runs over the same subtitles don't call the model again,
+// entries are keyed by Translator.CacheKey
+type Cache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// OpenCache loads the cache stored in path, a missing file results in an empty cache
+func OpenCache(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: map[string]CacheEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &c.entries)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse cache %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Get returns the entry stored for key
+func (c *Cache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	return e, ok
+}
+
+// Put stores an entry, it is only persisted when calling Save
+func (c *Cache) Put(key string, e CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = e
+}
+
+// Entries returns a copy of all stored entries
+func (c *Cache) Entries() []CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]CacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		out = append(out, e)
+	}
+	return out
+}
+
+// Save writes the cache to its file
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// hashStrings returns a hex sha256 of the strings, separated so that different splits give different hashes
+func hashStrings(parts []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
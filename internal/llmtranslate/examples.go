@@ -0,0 +1,118 @@
+package llmtranslate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/andresbott/substrans/internal/langid"
+)
+
+// Example is a reference translation shown to the model before the line to translate
+type Example struct {
+	SourceLang string   `json:"source_lang"`
+	TargetLang string   `json:"target_lang"`
+	Context    []string `json:"context"`
+	Source     string   `json:"source"`
+	Target     string   `json:"target"`
+}
+
+// Examples is a bank of reference translations for one or more language pairs
+type Examples []Example
+
+// LoadExamples reads a json file containing a list of examples
+func LoadExamples(path string) (Examples, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ex := Examples{}
+	err = json.Unmarshal(data, &ex)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse examples %s: %v", path, err)
+	}
+	for i, e := range ex {
+		if e.Source == "" || e.Target == "" || e.TargetLang == "" {
+			return nil, fmt.Errorf("invalid example %d in %s: source, target and target_lang are mandatory", i, path)
+		}
+	}
+	return ex, nil
+}
+
+// forPair returns the examples translating into target, the source language is only compared if set on both sides
+func (ex Examples) forPair(source, target string) Examples {
+	out := Examples{}
+	for _, e := range ex {
+		if !sameLang(e.TargetLang, target) {
+			continue
+		}
+		if e.SourceLang != "" && source != "" && !sameLang(e.SourceLang, source) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// mostSimilar returns the n examples with the most words in common with text, keeping their original order,
+// all examples are returned if n is 0 or there are not more than n
+func (ex Examples) mostSimilar(text string, n int) Examples {
+	if n <= 0 || len(ex) <= n {
+		return ex
+	}
+	words := wordSet(text)
+	idx := make([]int, len(ex))
+	scores := make([]float64, len(ex))
+	for i, e := range ex {
+		idx[i] = i
+		scores[i] = jaccard(words, wordSet(e.Source))
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return scores[idx[a]] > scores[idx[b]]
+	})
+	idx = idx[:n]
+	sort.Ints(idx)
+
+	out := make(Examples, 0, n)
+	for _, i := range idx {
+		out = append(out, ex[i])
+	}
+	return out
+}
+
+// sameLang compares languages by code if both are known, e.g. "spanish" and "es-ES" are the same
+func sameLang(a, b string) bool {
+	if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) {
+		return true
+	}
+	codeA, okA := langid.Code(a)
+	codeB, okB := langid.Code(b)
+	return okA && okB && codeA == codeB
+}
+
+func wordSet(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		set[w] = true
+	}
+	return set
+}
+
+// jaccard returns the size of the intersection divided by the size of the union of both sets
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
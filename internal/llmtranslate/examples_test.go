@@ -0,0 +1,116 @@
+package llmtranslate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// stubModel records the messages it receives and answers with a fixed text
+type stubModel struct {
+	answer   string
+	messages []llms.MessageContent
+}
+
+func (m *stubModel) GenerateContent(_ context.Context, messages []llms.MessageContent, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.messages = messages
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.answer}}}, nil
+}
+
+func (m *stubModel) Call(_ context.Context, _ string, _ ...llms.CallOption) (string, error) {
+	return m.answer, nil
+}
+
+const examplesJson = `[
+  {"source_lang": "english", "target_lang": "spanish", "context": ["Orlando, it's time to change shifts."], "source": "Where's your report?", "target": "¿Dónde está tu informe?"},
+  {"source_lang": "english", "target_lang": "spanish", "source": "My thoughts were elsewhere.", "target": "Tenía la cabeza en otra parte."},
+  {"source_lang": "english", "target_lang": "german", "source": "Where's your report?", "target": "Wo ist dein Bericht?"},
+  {"target_lang": "es", "source": "The wall guarantees the peace of the kingdom.", "target": "El muro garantiza la paz del reino."}
+]`
+
+func TestLoadExamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "examples.json")
+	if err := os.WriteFile(path, []byte(examplesJson), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ex, err := LoadExamples(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := func(ex Examples) []string {
+		out := []string{}
+		for _, e := range ex {
+			out = append(out, e.Source)
+		}
+		return out
+	}
+
+	spanish := ex.forPair("english", LangEs)
+	want := []string{"Where's your report?", "My thoughts were elsewhere.", "The wall guarantees the peace of the kingdom."}
+	if diff := cmp.Diff(want, sources(spanish)); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	got := spanish.mostSimilar("This wall guarantees the peace of every last person", 1)
+	if diff := cmp.Diff([]string{"The wall guarantees the peace of the kingdom."}, sources(got)); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"source": "Hello"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadExamples(path); err == nil {
+		t.Error("expected an error for an example without target")
+	}
+}
+
+func TestTranslateWithExamples(t *testing.T) {
+	model := &stubModel{answer: "Mis pensamientos estaban en otra parte."}
+	tr := &Translator{
+		client:       model,
+		prompt:       DefaultPrompt(),
+		sourceLang:   "english",
+		exampleCount: 1,
+		examples: Examples{
+			{TargetLang: "spanish", Context: []string{"Orlando, it's time to change shifts."}, Source: "Where's your report?", Target: "¿Dónde está tu informe?"},
+			{TargetLang: "german", Source: "Where's your report?", Target: "Wo ist dein Bericht?"},
+		},
+	}
+
+	got, err := tr.Translate(context.Background(), nil, nil, "My thoughts were elsewhere.", "spanish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != model.answer {
+		t.Errorf("unexpected translation %q", got)
+	}
+
+	roles := []llms.ChatMessageType{}
+	for _, m := range model.messages {
+		roles = append(roles, m.Role)
+	}
+	wantRoles := []llms.ChatMessageType{llms.ChatMessageTypeSystem, llms.ChatMessageTypeHuman, llms.ChatMessageTypeAI, llms.ChatMessageTypeHuman}
+	if diff := cmp.Diff(wantRoles, roles); diff != "" {
+		t.Fatalf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	text := func(m llms.MessageContent) string {
+		return m.Parts[0].(llms.TextContent).Text
+	}
+	if !strings.Contains(text(model.messages[1]), "- Orlando, it's time to change shifts.") ||
+		!strings.Contains(text(model.messages[1]), ">>>  'Where's your report?' <<<") {
+		t.Errorf("unexpected example prompt:\n%s", text(model.messages[1]))
+	}
+	if text(model.messages[2]) != "¿Dónde está tu informe?" {
+		t.Errorf("unexpected example answer: %s", text(model.messages[2]))
+	}
+	if !strings.Contains(text(model.messages[3]), ">>>  'My thoughts were elsewhere.' <<<") {
+		t.Errorf("unexpected prompt:\n%s", text(model.messages[3]))
+	}
+}
@@ -0,0 +1,88 @@
+package llmtranslate
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Message is a rendered message of the chat sent to the model
+type Message struct {
+	// Role is one of system, human or ai
+	Role string
+	Text string
+}
+
+// Exchange holds the chat sent to the model to translate a line and its raw reply
+type Exchange struct {
+	Model       string
+	Temperature float64
+	Messages    []Message
+	Reply       string
+	// PromptTokens is the size of the chat, Shrunk is true if context lines were dropped to fit the context window
+	PromptTokens int
+	Shrunk       bool
+}
+
+// System returns the system prompt
+func (e Exchange) System() string {
+	return e.last(string(llms.ChatMessageTypeSystem))
+}
+
+// User returns the message asking for the translation of the line, the one after the examples
+func (e Exchange) User() string {
+	return e.last(string(llms.ChatMessageTypeHuman))
+}
+
+func (e Exchange) last(role string) string {
+	for i := len(e.Messages) - 1; i >= 0; i-- {
+		if e.Messages[i].Role == role {
+			return e.Messages[i].Text
+		}
+	}
+	return ""
+}
+
+// String formats the exchange as plain text with a header before every message
+func (e Exchange) String() string {
+	var b strings.Builder
+	for _, m := range e.Messages {
+		b.WriteString("### " + m.Role + "\n")
+		b.WriteString(m.Text)
+		b.WriteString("\n\n")
+	}
+	if e.Reply != "" {
+		b.WriteString("### reply\n")
+		b.WriteString(e.Reply)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ChatRequest returns the body of an ollama /api/chat request with the same chat, so that the prompt can be
+// reproduced outside substrans, e.g. with curl
+func (e Exchange) ChatRequest() ([]byte, error) {
+	roles := map[string]string{
+		string(llms.ChatMessageTypeSystem): "system",
+		string(llms.ChatMessageTypeHuman):  "user",
+		string(llms.ChatMessageTypeAI):     "assistant",
+	}
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	req := struct {
+		Model    string             `json:"model"`
+		Messages []message          `json:"messages"`
+		Stream   bool               `json:"stream"`
+		Options  map[string]float64 `json:"options"`
+	}{
+		Model:   e.Model,
+		Options: map[string]float64{"temperature": e.Temperature},
+	}
+	for _, m := range e.Messages {
+		req.Messages = append(req.Messages, message{Role: roles[m.Role], Content: m.Text})
+	}
+	return json.MarshalIndent(req, "", "  ")
+}
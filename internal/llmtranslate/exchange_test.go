@@ -0,0 +1,76 @@
+package llmtranslate
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExchange(t *testing.T) {
+	model := &stubModel{answer: "¿Dónde está tu informe?"}
+	tr := &Translator{client: model, model: ModelLlama31, temp: 0.2, prompt: DefaultPrompt(), examples: Examples{
+		{TargetLang: LangEs, Source: "My thoughts were elsewhere.", Target: "Tenía la cabeza en otra parte."},
+	}}
+
+	ex, err := tr.Exchange(context.Background(), []string{"Orlando, it's time to change shifts."}, nil, "Where's your report?", "", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex.Reply != model.answer {
+		t.Errorf("unexpected reply %q", ex.Reply)
+	}
+	if !strings.Contains(ex.System(), "professional translator") {
+		t.Errorf("unexpected system prompt: %s", ex.System())
+	}
+	if !strings.Contains(ex.User(), "'Where's your report?'") || !strings.Contains(ex.User(), "Orlando") {
+		t.Errorf("expected the last user message to ask for the line: %s", ex.User())
+	}
+	if !strings.Contains(ex.String(), "### ai\nTenía la cabeza en otra parte.") || !strings.HasSuffix(ex.String(), "### reply\n¿Dónde está tu informe?\n") {
+		t.Errorf("unexpected text format:\n%s", ex.String())
+	}
+
+	rendered, err := tr.Render([]string{"Orlando, it's time to change shifts."}, nil, "Where's your report?", "", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.Reply = ""
+	if diff := cmp.Diff(ex, rendered); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	body, err := ex.ChatRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := struct {
+		Model    string
+		Messages []struct{ Role string }
+	}{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	roles := []string{}
+	for _, m := range req.Messages {
+		roles = append(roles, m.Role)
+	}
+	if req.Model != ModelLlama31 {
+		t.Errorf("unexpected model %q", req.Model)
+	}
+	if diff := cmp.Diff([]string{"system", "user", "assistant", "user"}, roles); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestExchangeWithHint(t *testing.T) {
+	tr := &Translator{client: &stubModel{answer: "¿Y tu informe?"}, prompt: DefaultPrompt()}
+	ex, err := tr.ExchangeWithHint(context.Background(), nil, nil, "Where's your report?", "", LangEs, "it must be shorter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ex.User(), "Take into account the following note: it must be shorter") {
+		t.Errorf("expected the hint in the prompt:\n%s", ex.User())
+	}
+}
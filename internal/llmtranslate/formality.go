@@ -0,0 +1,90 @@
+package llmtranslate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andresbott/substrans/internal/langid"
+)
+
+// formality levels of the translated speech
+const (
+	FormalityFormal   = "formal"
+	FormalityInformal = "informal"
+	// FormalityAuto lets the model choose the register based on the characters and their relationships
+	FormalityAuto = "auto"
+)
+
+// ValidFormality returns an error if f is not empty or one of the supported formality levels
+func ValidFormality(f string) error {
+	switch f {
+	case "", FormalityFormal, FormalityInformal, FormalityAuto:
+		return nil
+	}
+	return fmt.Errorf("unsupported formality: %s, use formal, informal or auto", f)
+}
+
+// registers holds the way formal and informal speech is called in languages that make the distinction
+var registers = map[string][2]string{
+	"es": {"usted", "tú"},
+	"de": {"Sie", "du"},
+	"fr": {"vous", "tu"},
+	"it": {"Lei", "tu"},
+	"pt": {"o senhor / a senhora", "você / tu"},
+	"nl": {"u", "jij"},
+	"ca": {"vostè", "tu"},
+	"pl": {"Pan / Pani", "ty"},
+	"sv": {"ni", "du"},
+	"ru": {"вы", "ты"},
+	"ja": {"keigo", "casual speech"},
+	"ko": {"jondaetmal", "banmal"},
+}
+
+// formalityInstruction returns the sentence added to the prompt for a formality level and target language
+func formalityInstruction(formality, lang string) string {
+	var formal, informal string
+	if code, ok := langid.Code(lang); ok {
+		if r, found := registers[code]; found {
+			formal = " (" + r[0] + ")"
+			informal = " (" + r[1] + ")"
+		}
+	}
+	switch formality {
+	case FormalityFormal:
+		return "Address people using the formal register" + formal + "."
+	case FormalityInformal:
+		return "Address people using the informal register" + informal + "."
+	case FormalityAuto:
+		return "Choose between the formal" + formal + " and informal" + informal +
+			" register based on the characters and their relationship, and keep it consistent."
+	}
+	return ""
+}
+
+// Voice overrides the formality and tone for the lines said by a character
+type Voice struct {
+	// Speaker is the label used in the subtitles or the name of a character of the show notes
+	Speaker   string
+	Formality string
+	Tone      string
+}
+
+// voice returns the effective formality and tone for a speaker
+func (t *Translator) voice(label string, speaker Character) (formality, tone string) {
+	formality, tone = t.formality, t.tone
+	if label == "" {
+		return formality, tone
+	}
+	for _, v := range t.voices {
+		if strings.EqualFold(v.Speaker, label) || speaker.matches(v.Speaker) {
+			if v.Formality != "" {
+				formality = v.Formality
+			}
+			if v.Tone != "" {
+				tone = v.Tone
+			}
+			break
+		}
+	}
+	return formality, tone
+}
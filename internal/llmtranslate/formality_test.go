@@ -0,0 +1,75 @@
+package llmtranslate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormalityInstruction(t *testing.T) {
+	tcs := []struct {
+		formality string
+		lang      string
+		want      string
+	}{
+		{formality: FormalityFormal, lang: LangEs, want: "Address people using the formal register (usted)."},
+		{formality: FormalityInformal, lang: "de-DE", want: "Address people using the informal register (du)."},
+		{formality: FormalityFormal, lang: "japanese", want: "Address people using the formal register (keigo)."},
+		{formality: FormalityFormal, lang: "klingon", want: "Address people using the formal register."},
+		{formality: FormalityAuto, lang: "french", want: "Choose between the formal (vous) and informal (tu) register based on the characters and their relationship, and keep it consistent."},
+		{formality: "", lang: LangEs, want: ""},
+	}
+	for _, tc := range tcs {
+		got := formalityInstruction(tc.formality, tc.lang)
+		if got != tc.want {
+			t.Errorf("%s %s: expected %q, got %q", tc.formality, tc.lang, tc.want, got)
+		}
+	}
+
+	if err := ValidFormality("polite"); err == nil {
+		t.Error("expected an error for an unsupported formality")
+	}
+}
+
+func TestVoices(t *testing.T) {
+	tr := &Translator{
+		prompt:    DefaultPrompt(),
+		formality: FormalityInformal,
+		tone:      "casual, this is a comedy",
+		notes: ShowNotes{Characters: []Character{
+			{Name: "Remedios Custodio", Aliases: []string{"Remedios"}},
+		}},
+		voices: []Voice{
+			{Speaker: "Remedios Custodio", Formality: FormalityFormal},
+			{Speaker: "Baraja", Tone: "stern"},
+		},
+	}
+
+	tcs := []struct {
+		speaker string
+		want    []string
+	}{
+		{speaker: "", want: []string{"using the following tone: casual, this is a comedy.", "informal register (tú)"}},
+		{speaker: "Remedios", want: []string{"using the following tone: casual, this is a comedy.", "formal register (usted)"}},
+		{speaker: "baraja", want: []string{"using the following tone: stern.", "informal register (tú)"}},
+	}
+	for _, tc := range tcs {
+		system, _, err := tr.prompt.render(tr.message(nil, nil, "Where's your report?", tc.speaker, "spanish", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(system, want) {
+				t.Errorf("speaker %q: system prompt does not contain %q:\n%s", tc.speaker, want, system)
+			}
+		}
+	}
+
+	tr = &Translator{prompt: DefaultPrompt()}
+	system, _, err := tr.prompt.render(tr.message(nil, nil, "Where's your report?", "", "spanish", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(system, "trying to keep the sentiment of the conversation and the tone as close as possible to the original.") {
+		t.Errorf("expected the default tone instruction:\n%s", system)
+	}
+}
@@ -0,0 +1,56 @@
+package llmtranslate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GlossaryEntry is a term with its mandatory translation
+type GlossaryEntry struct {
+	Source string
+	Target string
+}
+
+// Glossary is a list of terms that must always be translated in the same way
+type Glossary []GlossaryEntry
+
+// LoadGlossary reads a glossary file, every line holds a term and its translation separated by "=",
+// e.g. "Sacred Kingdom = Reino Sagrado", empty lines and lines starting with # are ignored
+func LoadGlossary(path string) (Glossary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g := Glossary{}
+	scanner := bufio.NewScanner(f)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source, target, found := strings.Cut(line, "=")
+		if !found || strings.TrimSpace(source) == "" {
+			return nil, fmt.Errorf("invalid glossary entry in %s line %d: %q", path, n, line)
+		}
+		g = append(g, GlossaryEntry{Source: strings.TrimSpace(source), Target: strings.TrimSpace(target)})
+	}
+	return g, scanner.Err()
+}
+
+// matching returns the entries whose term appears in the text, ignoring case
+func (g Glossary) matching(text string) Glossary {
+	text = strings.ToLower(text)
+	out := Glossary{}
+	for _, e := range g {
+		if strings.Contains(text, strings.ToLower(e.Source)) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
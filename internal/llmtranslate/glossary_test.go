@@ -0,0 +1,60 @@
+package llmtranslate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadGlossary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "glossary.txt")
+	content := `# overlord terms
+Sacred Kingdom = Reino Sagrado
+
+Abelion Hills = Colinas Abelion
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadGlossary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Glossary{
+		{Source: "Sacred Kingdom", Target: "Reino Sagrado"},
+		{Source: "Abelion Hills", Target: "Colinas Abelion"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	msg := chatMsg{
+		Line:     "The borders of the sacred kingdom are guarded by a massive wall",
+		Lang:     LangEs,
+		Glossary: got.matching("The borders of the sacred kingdom are guarded by a massive wall"),
+	}
+	prompt, err := msg.FormatMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "- Sacred Kingdom: Reino Sagrado") {
+		t.Errorf("prompt does not contain the matching glossary entry:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "Abelion") {
+		t.Errorf("prompt contains a glossary entry not present in the line:\n%s", prompt)
+	}
+}
+
+func TestLoadGlossaryInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "glossary.txt")
+	if err := os.WriteFile(path, []byte("Sacred Kingdom"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGlossary(path); err == nil {
+		t.Error("expected an error for an entry without translation")
+	}
+}
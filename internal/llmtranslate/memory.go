@@ -0,0 +1,229 @@
+package llmtranslate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andresbott/substrans/internal/langtag"
+	"github.com/andresbott/substrans/internal/tmx"
+)
+
+// defaultFuzzyScore is the minimum similarity of a fuzzy match, as commonly used by CAT tools
+const defaultFuzzyScore = 0.75
+
+// defaultFuzzyCount is the amount of fuzzy matches sent to the model for a line
+const defaultFuzzyCount = 3
+
+// Memory is a translation memory consulted before calling the model: an exact match of the line is used
+// as its translation and fuzzy matches are sent to the model as examples
+type Memory struct {
+	entries Examples
+}
+
+// MemoryMatch is an entry of the memory similar to a line, the score goes from 0 to 1
+type MemoryMatch struct {
+	Example
+	Score float64
+}
+
+// NewMemory creates a memory out of source and target pairs
+func NewMemory(entries Examples) *Memory {
+	return &Memory{entries: entries}
+}
+
+// LoadMemory reads a TMX file, every pair of languages of a unit becomes an entry; if the file sets a
+// source language only the pairs translating from it are used
+func LoadMemory(path string) (*Memory, error) {
+	doc, err := tmx.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := Examples{}
+	for _, u := range doc.Units {
+		for _, src := range u.Variants {
+			if doc.SourceLang != "" && doc.SourceLang != "*all*" && !strings.EqualFold(src.Lang, doc.SourceLang) {
+				continue
+			}
+			for _, tgt := range u.Variants {
+				if tgt.Lang == src.Lang {
+					continue
+				}
+				entries = append(entries, Example{SourceLang: src.Lang, TargetLang: tgt.Lang, Source: src.Text, Target: tgt.Text})
+			}
+		}
+	}
+	return NewMemory(entries), nil
+}
+
+// ExportTMX converts cache entries to a TMX document, sourceLang is used for the entries that do not store
+// their source language; the same translation stored for different contexts is exported once
+func ExportTMX(entries []CacheEntry, sourceLang string) (tmx.Document, error) {
+	doc := tmx.Document{}
+	seen := map[string]bool{}
+	sources := map[string]bool{}
+	for _, e := range entries {
+		src := e.SourceLang
+		if src == "" {
+			src = sourceLang
+		}
+		if src == "" {
+			return doc, fmt.Errorf("the source language of %q is unknown", e.Source)
+		}
+		srcCode, err := tmxLang(src)
+		if err != nil {
+			return doc, err
+		}
+		tgtCode, err := tmxLang(e.Lang)
+		if err != nil {
+			return doc, err
+		}
+		key := hashStrings([]string{srcCode, e.Source, tgtCode, e.Target})
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sources[srcCode] = true
+		doc.Units = append(doc.Units, tmx.Unit{Variants: []tmx.Variant{
+			{Lang: srcCode, Text: e.Source},
+			{Lang: tgtCode, Text: e.Target},
+		}})
+	}
+	sort.SliceStable(doc.Units, func(i, j int) bool {
+		a, b := doc.Units[i].Variants, doc.Units[j].Variants
+		if a[0].Text != b[0].Text {
+			return a[0].Text < b[0].Text
+		}
+		if a[1].Lang != b[1].Lang {
+			return a[1].Lang < b[1].Lang
+		}
+		return a[1].Text < b[1].Text
+	})
+	doc.SourceLang = "*all*"
+	if len(sources) == 1 {
+		for code := range sources {
+			doc.SourceLang = code
+		}
+	}
+	return doc, nil
+}
+
+// tmxLang converts the language of an entry, stored as a description like "Portuguese (Brazil)", to its code
+func tmxLang(lang string) (string, error) {
+	if tag, ok := langtag.ParseName(lang); ok {
+		return tag.String(), nil
+	}
+	tag, err := langtag.Parse(lang)
+	if err != nil {
+		return "", fmt.Errorf("invalid language of cache entry: %v", err)
+	}
+	return tag.String(), nil
+}
+
+// Len returns the amount of entries of the memory
+func (m *Memory) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.entries)
+}
+
+// Exact returns the translation of a line found as is in the memory, differences in white space are ignored
+func (m *Memory) Exact(text, source, target string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	text = normalizeSpace(text)
+	for _, e := range m.forPair(source, target) {
+		if normalizeSpace(e.Source) == text {
+			return e.Target, true
+		}
+	}
+	return "", false
+}
+
+// Fuzzy returns up to n entries with a similarity of at least minScore, the most similar first;
+// exact matches are not included
+func (m *Memory) Fuzzy(text, source, target string, minScore float64, n int) []MemoryMatch {
+	if m == nil || n <= 0 {
+		return nil
+	}
+	text = normalizeSpace(text)
+	out := []MemoryMatch{}
+	seen := map[string]bool{}
+	for _, e := range m.forPair(source, target) {
+		src := normalizeSpace(e.Source)
+		if src == text || seen[src] {
+			continue
+		}
+		score := similarity(text, src, minScore)
+		if score >= minScore {
+			seen[src] = true
+			out = append(out, MemoryMatch{Example: e, Score: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Score > out[j].Score
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// forPair returns the entries translating into target including its script and region, an es-MX entry is not
+// used for es-ES
+func (m *Memory) forPair(source, target string) Examples {
+	out := Examples{}
+	for _, e := range m.entries.forPair(source, target) {
+		if sameTag(e.TargetLang, target) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// sameTag compares languages including their script and region, e.g. "es-ES" and "Spanish (Spain)" are the same
+// but "es-MX" is not; languages that cannot be parsed are compared as text
+func sameTag(a, b string) bool {
+	tagA, errA := tmxLang(a)
+	tagB, errB := tmxLang(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	return tagA == tagB
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// similarity returns 1 minus the edit distance between the texts relative to the longest one, ignoring case;
+// 0 is returned without computing the distance if the lengths are too different to reach minScore
+func similarity(a, b string, minScore float64) float64 {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	if float64(min(len(ra), len(rb)))/float64(longest) < minScore {
+		return 0
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
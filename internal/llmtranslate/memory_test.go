@@ -0,0 +1,168 @@
+package llmtranslate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andresbott/substrans/internal/tmx"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testMemory(t *testing.T) *Memory {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "memory.tmx")
+	err := tmx.Write(path, tmx.Document{
+		SourceLang: "en-US",
+		Units: []tmx.Unit{
+			{Variants: []tmx.Variant{{Lang: "en-US", Text: "Where's your report?"}, {Lang: "es-ES", Text: "¿Dónde está tu informe?"}}},
+			{Variants: []tmx.Variant{{Lang: "en-US", Text: "Where is your report, soldier?"}, {Lang: "es-ES", Text: "¿Dónde está tu informe, soldado?"}}},
+			{Variants: []tmx.Variant{{Lang: "en-US", Text: "My apologies."}, {Lang: "de-DE", Text: "Entschuldigung."}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadMemory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMemory(t *testing.T) {
+	m := testMemory(t)
+	if m.Len() != 3 {
+		t.Errorf("expected 3 entries, got %d", m.Len())
+	}
+
+	got, ok := m.Exact("Where's  your report?", "English", LangEs)
+	if !ok || got != "¿Dónde está tu informe?" {
+		t.Errorf("unexpected exact match %q %v", got, ok)
+	}
+	if _, ok := m.Exact("My apologies.", "English", LangEs); ok {
+		t.Error("expected no match for another target language")
+	}
+
+	fuzzy := m.Fuzzy("Where is your report, sergeant?", "English", LangEs, 0.75, 3)
+	if len(fuzzy) != 1 || fuzzy[0].Target != "¿Dónde está tu informe, soldado?" {
+		t.Fatalf("unexpected fuzzy matches: %+v", fuzzy)
+	}
+	if fuzzy[0].Score < 0.75 || fuzzy[0].Score >= 1 {
+		t.Errorf("unexpected score %f", fuzzy[0].Score)
+	}
+	if got := m.Fuzzy("Good morning", "English", LangEs, 0.75, 3); len(got) != 0 {
+		t.Errorf("expected no fuzzy matches, got %+v", got)
+	}
+	var empty *Memory
+	if _, ok := empty.Exact("Where's your report?", "", LangEs); ok {
+		t.Error("expected no match without a memory")
+	}
+}
+
+func TestMemoryRegion(t *testing.T) {
+	m := NewMemory(Examples{
+		{SourceLang: "en-US", TargetLang: "es-MX", Source: "Where's your car?", Target: "¿Dónde está tu carro?"},
+		{SourceLang: "en-US", TargetLang: "es-ES", Source: "Where's your car?", Target: "¿Dónde está tu coche?"},
+		{SourceLang: "en-US", TargetLang: "es-MX", Source: "Hello, friend.", Target: "Hola, cuate."},
+	})
+	tcs := []struct {
+		text, target string
+		want         string
+		wantOk       bool
+	}{
+		{text: "Where's your car?", target: "Spanish (Spain)", want: "¿Dónde está tu coche?", wantOk: true},
+		{text: "Where's your car?", target: "es-MX", want: "¿Dónde está tu carro?", wantOk: true},
+		{text: "Hello, friend.", target: "es-ES"},
+		{text: "Hello, friend.", target: "es"},
+	}
+	if got := m.Fuzzy("Hello, my friend.", "English", "es-ES", 0.75, 3); len(got) != 0 {
+		t.Errorf("expected no fuzzy matches of another region, got %+v", got)
+	}
+	for _, tc := range tcs {
+		got, ok := m.Exact(tc.text, "English", tc.target)
+		if ok != tc.wantOk || got != tc.want {
+			t.Errorf("Exact(%q, %q) = %q %v, want %q %v", tc.text, tc.target, got, ok, tc.want, tc.wantOk)
+		}
+	}
+}
+
+func TestMemoryFuzzyOnce(t *testing.T) {
+	tr := &Translator{client: &stubModel{}, model: ModelLlama31, prompt: DefaultPrompt(), sourceLang: "English",
+		memory: testMemory(t), fuzzyScore: defaultFuzzyScore, fuzzyCount: defaultFuzzyCount}
+	first := tr.fuzzyMatches("Where is your report, sergeant?", LangEs)
+	// changing the entries shows whether the matches are searched again
+	tr.memory.entries = tr.memory.entries[2:]
+	second := tr.fuzzyMatches("Where is your report, sergeant?", LangEs)
+	if len(first) != 1 || !cmp.Equal(first, second) {
+		t.Errorf("expected the matches of the line to be kept, got %+v and %+v", first, second)
+	}
+	if got := tr.fuzzyMatches("Where is your report, sergeant?", "es-MX"); len(got) != 0 {
+		t.Errorf("expected no matches of another region, got %+v", got)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tcs := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "kitten", b: "sitting", want: 1 - 3.0/7},
+		{a: "Hello", b: "hello", want: 1},
+		{a: "abc", b: "abcdabcd", want: 0},
+		{a: "", b: "", want: 1},
+	}
+	for _, tc := range tcs {
+		if got := similarity(tc.a, tc.b, 0.5); got != tc.want {
+			t.Errorf("similarity(%q, %q) = %f, want %f", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestMemoryInPrompt(t *testing.T) {
+	tr := &Translator{client: &stubModel{}, model: ModelLlama31, prompt: DefaultPrompt(), sourceLang: "English",
+		memory: testMemory(t), fuzzyScore: defaultFuzzyScore, fuzzyCount: defaultFuzzyCount}
+
+	ex, err := tr.Render(nil, nil, "Where is your report, sergeant?", "", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// system, the fuzzy match as a previous turn and the line
+	if len(ex.Messages) != 4 || ex.Messages[2].Text != "¿Dónde está tu informe, soldado?" {
+		t.Fatalf("expected the fuzzy match as an example, got:\n%s", ex.String())
+	}
+	if !strings.Contains(ex.Messages[1].Text, "Where is your report, soldier?") {
+		t.Errorf("unexpected example message: %s", ex.Messages[1].Text)
+	}
+	if got, ok := tr.MemoryMatch("Where's your report?", LangEs); !ok || got != "¿Dónde está tu informe?" {
+		t.Errorf("unexpected memory match %q %v", got, ok)
+	}
+}
+
+func TestExportTMX(t *testing.T) {
+	entries := []CacheEntry{
+		{Source: "Where's your report?", Target: "¿Dónde está tu informe?", Lang: "Spanish (Spain)", SourceLang: "English"},
+		// the same line translated with another context
+		{Source: "Where's your report?", Target: "¿Dónde está tu informe?", Lang: "Spanish (Spain)", SourceLang: "English"},
+		{Source: "My apologies.", Target: "Desculpe.", Lang: "Portuguese (Brazil)"},
+	}
+	doc, err := ExportTMX(entries, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tmx.Document{
+		SourceLang: "en",
+		Units: []tmx.Unit{
+			{Variants: []tmx.Variant{{Lang: "en", Text: "My apologies."}, {Lang: "pt-BR", Text: "Desculpe."}}},
+			{Variants: []tmx.Variant{{Lang: "en", Text: "Where's your report?"}, {Lang: "es-ES", Text: "¿Dónde está tu informe?"}}},
+		},
+	}
+	if diff := cmp.Diff(want, doc); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	_, err = ExportTMX(entries, "")
+	if err == nil || !strings.Contains(err.Error(), "source language") {
+		t.Errorf("expected an error for entries without source language, got %v", err)
+	}
+}
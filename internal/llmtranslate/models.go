@@ -0,0 +1,81 @@
+package llmtranslate
+
+import (
+	"strings"
+)
+
+// ModelInfo describes the capabilities of a known model
+type ModelInfo struct {
+	Name string
+	// ContextLength is the context window in tokens requested from the server, it can be lower than the
+	// maximum the model supports to keep memory usage reasonable
+	ContextLength int
+	// Temperature is the recommended temperature for translations
+	Temperature float64
+	// BatchSize is the recommended amount of items translated in parallel
+	BatchSize int
+	// JSONMode is true if the model reliably answers with valid json when asked to
+	JSONMode bool
+	// Strengths are the codes of the languages the model is known to translate well
+	Strengths []string
+}
+
+// registry holds the models known to work for subtitle translation
+var registry = []ModelInfo{
+	{Name: ModelLlama3, ContextLength: 8192, Temperature: 0.3, BatchSize: 2, JSONMode: true,
+		Strengths: []string{"en", "es", "fr", "de", "it", "pt"}},
+	{Name: ModelLlama31, ContextLength: 8192, Temperature: 0.3, BatchSize: 2, JSONMode: true,
+		Strengths: []string{"en", "es", "fr", "de", "it", "pt", "hi", "th"}},
+	{Name: ModelLlama32, ContextLength: 8192, Temperature: 0.2, BatchSize: 4, JSONMode: true,
+		Strengths: []string{"en", "es", "fr", "de", "it", "pt", "hi", "th"}},
+	{Name: ModelPhi35mini, ContextLength: 8192, Temperature: 0.2, BatchSize: 4, JSONMode: false,
+		Strengths: []string{"en"}},
+	{Name: ModelPhi4, ContextLength: 16384, Temperature: 0.3, BatchSize: 1, JSONMode: true,
+		Strengths: []string{"en", "es", "fr", "de", "it", "pt"}},
+	{Name: ModelGemma3, ContextLength: 8192, Temperature: 0.3, BatchSize: 1, JSONMode: true,
+		Strengths: []string{"en", "es", "fr", "de", "it", "pt", "nl", "pl", "ru", "ja", "ko", "zh"}},
+	{Name: ModelMixtral, ContextLength: 8192, Temperature: 0.3, BatchSize: 1, JSONMode: true,
+		Strengths: []string{"en", "es", "fr", "de", "it"}},
+	{Name: ModelMistral7b, ContextLength: 8192, Temperature: 0.3, BatchSize: 2, JSONMode: false,
+		Strengths: []string{"en", "fr"}},
+	{Name: MistralNemo, ContextLength: 16384, Temperature: 0.3, BatchSize: 2, JSONMode: true,
+		Strengths: []string{"en", "es", "fr", "de", "it", "pt", "ja", "ko", "zh", "ar", "hi"}},
+}
+
+// LookupModel returns the capabilities of a model, names without tag or with the "latest" tag
+// match the registered name, e.g. "mixtral" and "mixtral:latest" match "mixtral:8x7b"
+func LookupModel(name string) (ModelInfo, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, m := range registry {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	base, tag, _ := strings.Cut(name, ":")
+	if tag != "" && tag != "latest" {
+		return ModelInfo{}, false
+	}
+	for _, m := range registry {
+		if registeredBase, _, _ := strings.Cut(m.Name, ":"); registeredBase == base {
+			return m, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// KnownModels returns the registered models
+func KnownModels() []ModelInfo {
+	out := make([]ModelInfo, len(registry))
+	copy(out, registry)
+	return out
+}
+
+// StrongIn returns true if the model is known to translate well into the language with the given code
+func (m ModelInfo) StrongIn(code string) bool {
+	for _, s := range m.Strengths {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
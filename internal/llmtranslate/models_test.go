@@ -0,0 +1,45 @@
+package llmtranslate
+
+import "testing"
+
+func TestLookupModel(t *testing.T) {
+	tcs := []struct {
+		name  string
+		want  string
+		found bool
+	}{
+		{name: "llama3.1:8b", want: ModelLlama31, found: true},
+		{name: "Phi4:14B", want: ModelPhi4, found: true},
+		{name: "mixtral", want: ModelMixtral, found: true},
+		{name: "mistral-nemo:latest", want: MistralNemo, found: true},
+		{name: "llama3.1:70b", found: false},
+		{name: "gpt-4o", found: false},
+	}
+	for _, tc := range tcs {
+		got, found := LookupModel(tc.name)
+		if found != tc.found || got.Name != tc.want {
+			t.Errorf("LookupModel(%q) = %q, %v, want %q, %v", tc.name, got.Name, found, tc.want, tc.found)
+		}
+	}
+
+	info, _ := LookupModel(ModelGemma3)
+	if !info.StrongIn("ja") || info.StrongIn("xx") {
+		t.Errorf("unexpected strengths for %s: %v", info.Name, info.Strengths)
+	}
+}
+
+func TestPromptTokens(t *testing.T) {
+	tr := &Translator{prompt: DefaultPrompt()}
+	short, err := tr.PromptTokens(nil, nil, "Where's your report?", "", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := []string{"An area also located near the Slain Theocracy.", "Orlando, it's time to change shifts."}
+	long, err := tr.PromptTokens(context, context, "Where's your report?", "", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short <= 0 || long-short < CountTokens(context[0])+CountTokens(context[1]) {
+		t.Errorf("unexpected token estimation: %d without context, %d with context", short, long)
+	}
+}
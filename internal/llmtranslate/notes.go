@@ -0,0 +1,89 @@
+package llmtranslate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ShowNotes describes the show being translated, it is added to the system prompt so that the model knows
+// who is speaking and to whom, e.g. to use the right gender and formality
+type ShowNotes struct {
+	Synopsis      string         `json:"synopsis"`
+	Characters    []Character    `json:"characters"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+// Character is a person appearing in the show
+type Character struct {
+	Name string `json:"name"`
+	// Aliases are other names used for the character, e.g. in the Name field of ASS files
+	Aliases     []string `json:"aliases"`
+	Gender      string   `json:"gender"`
+	Description string   `json:"description"`
+}
+
+// Relationship describes how a character addresses another one
+type Relationship struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Relation is free text, e.g. "subordinate of"
+	Relation string `json:"relation"`
+	// Formality of the speech from one character to the other, e.g. "formal" or "informal"
+	Formality string `json:"formality"`
+}
+
+// LoadShowNotes reads a json file with the show notes
+func LoadShowNotes(path string) (ShowNotes, error) {
+	n := ShowNotes{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return n, err
+	}
+	err = json.Unmarshal(data, &n)
+	if err != nil {
+		return n, fmt.Errorf("unable to parse show notes %s: %v", path, err)
+	}
+	for i, c := range n.Characters {
+		if c.Name == "" {
+			return n, fmt.Errorf("invalid character %d in %s: name is mandatory", i, path)
+		}
+	}
+	return n, nil
+}
+
+// character returns the character matching a speaker label, if no character is found
+// only the name is set
+func (n ShowNotes) character(speaker string) Character {
+	speaker = strings.TrimSpace(speaker)
+	if speaker == "" {
+		return Character{}
+	}
+	for _, c := range n.Characters {
+		if c.matches(speaker) {
+			return c
+		}
+	}
+	// labels often contain only part of the name, e.g. "Neia" for "Neia Baraja"
+	for _, c := range n.Characters {
+		for _, word := range strings.Fields(c.Name) {
+			if strings.EqualFold(word, speaker) {
+				return c
+			}
+		}
+	}
+	return Character{Name: speaker}
+}
+
+func (c Character) matches(name string) bool {
+	if strings.EqualFold(c.Name, name) {
+		return true
+	}
+	for _, a := range c.Aliases {
+		if strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package llmtranslate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tmc/langchaingo/llms"
+)
+
+const notesJson = `{
+  "synopsis": "The Sacred Kingdom is attacked by the demon emperor Jaldabaoth.",
+  "characters": [
+    {"name": "Neia Baraja", "gender": "female", "description": "squire of the Paladin Order"},
+    {"name": "Orlando Campano", "aliases": ["Orlando"], "gender": "male", "description": "veteran soldier"}
+  ],
+  "relationships": [
+    {"from": "Orlando Campano", "to": "Pavel Baraja", "relation": "subordinate of", "formality": "formal"}
+  ]
+}`
+
+func TestLoadShowNotes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.json")
+	if err := os.WriteFile(path, []byte(notesJson), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := LoadShowNotes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		speaker string
+		want    Character
+	}{
+		{speaker: "orlando", want: notes.Characters[1]},
+		{speaker: "Neia", want: notes.Characters[0]},
+		{speaker: "Remedios", want: Character{Name: "Remedios"}},
+		{speaker: "", want: Character{}},
+	}
+	for _, tc := range tcs {
+		if diff := cmp.Diff(tc.want, notes.character(tc.speaker)); diff != "" {
+			t.Errorf("speaker %q: Mismatch (-expected +actual):\n%s", tc.speaker, diff)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`{"characters": [{"gender": "male"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadShowNotes(path); err == nil {
+		t.Error("expected an error for a character without name")
+	}
+}
+
+func TestTranslateWithShowNotes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.json")
+	if err := os.WriteFile(path, []byte(notesJson), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := LoadShowNotes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := &stubModel{answer: "Mis disculpas, sargento Baraja."}
+	cfg := settings{}
+	WithShowNotes(notes)(&cfg)
+	tr := &Translator{client: model, prompt: DefaultPrompt(), notes: cfg.notes, synopsis: cfg.synopsis}
+
+	prev := []string{"Baraja: Where's your report?"}
+	_, err = tr.TranslateAs(context.Background(), prev, nil, "My apologies, Sergeant Baraja.", "Orlando", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	system := model.messages[0].Parts[0].(llms.TextContent).Text
+	for _, want := range []string{
+		"Synopsis of the show: The Sacred Kingdom is attacked by the demon emperor Jaldabaoth.",
+		"- Neia Baraja (female): squire of the Paladin Order",
+		"- Orlando Campano is subordinate of Pavel Baraja, speech is formal",
+	} {
+		if !strings.Contains(system, want) {
+			t.Errorf("system prompt does not contain %q:\n%s", want, system)
+		}
+	}
+	user := model.messages[1].Parts[0].(llms.TextContent).Text
+	for _, want := range []string{
+		"- Baraja: Where's your report?",
+		"- Orlando: My apologies, Sergeant Baraja.",
+		"The line is said by Orlando Campano (male).",
+	} {
+		if !strings.Contains(user, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, user)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package llmtranslate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// probeLine is translated to measure the latency of the model
+const probeLine = "Where's your report?"
+
+// ProbeResult holds the outcome of a probe translation
+type ProbeResult struct {
+	Translation string
+	Latency     time.Duration
+	// JSONMode is true if the model answered with valid json when asked to
+	JSONMode  bool
+	JSONError string
+}
+
+// Probe translates a short line to measure the latency of the model and checks whether json mode works
+func (t *Translator) Probe(ctx context.Context, lang string) (ProbeResult, error) {
+	r := ProbeResult{}
+	start := time.Now()
+	translation, err := t.Translate(ctx, nil, nil, probeLine, lang)
+	if err != nil {
+		return r, err
+	}
+	r.Latency = time.Since(start)
+	r.Translation = translation
+
+	content := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You are a professional translator, answer only with json."),
+		llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(
+			"Translate the line %q into %s and answer with a json object with the key \"translation\".", probeLine, lang)),
+	}
+	resp, err := t.client.GenerateContent(ctx, content, llms.WithTemperature(t.temp), llms.WithJSONMode())
+	if err != nil {
+		r.JSONError = err.Error()
+		return r, nil
+	}
+	answer := struct {
+		Translation string `json:"translation"`
+	}{}
+	err = json.Unmarshal([]byte(resp.Choices[0].Content), &answer)
+	switch {
+	case err != nil:
+		r.JSONError = fmt.Sprintf("invalid json: %v", err)
+	case answer.Translation == "":
+		r.JSONError = "the json answer has no translation"
+	default:
+		r.JSONMode = true
+	}
+	return r, nil
+}
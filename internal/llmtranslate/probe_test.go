@@ -0,0 +1,33 @@
+package llmtranslate
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	tcs := []struct {
+		name     string
+		answer   string
+		jsonMode bool
+	}{
+		{name: "json answer", answer: `{"translation": "¿Dónde está tu informe?"}`, jsonMode: true},
+		{name: "plain answer", answer: "¿Dónde está tu informe?", jsonMode: false},
+		{name: "json without translation", answer: `{"text": "¿Dónde está tu informe?"}`, jsonMode: false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := &Translator{client: &stubModel{answer: tc.answer}, prompt: DefaultPrompt()}
+			got, err := tr.Probe(context.Background(), "spanish")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Translation != tc.answer {
+				t.Errorf("unexpected translation %q", got.Translation)
+			}
+			if got.JSONMode != tc.jsonMode || (got.JSONError == "") != tc.jsonMode {
+				t.Errorf("unexpected json mode %v: %s", got.JSONMode, got.JSONError)
+			}
+		})
+	}
+}
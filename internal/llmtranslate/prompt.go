@@ -0,0 +1,125 @@
+package llmtranslate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Prompt holds the system and user templates used to build the messages sent to the model,
+// both templates are executed with the fields of chatMsg
+type Prompt struct {
+	system *template.Template
+	user   *template.Template
+	hash   string
+}
+
+// defaultSystem is the system template used if none is provided
+const defaultSystem = systemPromt + `{{if .Synopsis}}
+Synopsis of the show: {{.Synopsis}}{{end}}{{if .Characters}}
+Characters:
+{{range .Characters}}- {{.Name}}{{if .Gender}} ({{.Gender}}){{end}}{{if .Description}}: {{.Description}}{{end}}
+{{end}}{{end}}{{if .Relationships}}
+Relationships between characters:
+{{range .Relationships}}- {{.From}}{{if .Relation}} is {{.Relation}}{{end}} {{.To}}{{if .Formality}}, speech is {{.Formality}}{{end}}
+{{end}}{{end}}`
+
+// defaultPrompt is parsed and validated once, prompts are not modified after they are created
+var defaultPrompt = func() *Prompt {
+	p, err := NewPrompt(defaultSystem, tmpl)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default prompt: %v", err))
+	}
+	return p
+}()
+
+// DefaultPrompt returns the built-in prompt
+func DefaultPrompt() *Prompt {
+	return defaultPrompt
+}
+
+// validationMsg is used to verify that templates can be executed before translating anything
+var validationMsg = chatMsg{
+	PrevContext:  []string{"Orlando, it's time to change shifts."},
+	PostContext:  []string{"But there are no changes to report today."},
+	Line:         "My thoughts were elsewhere.",
+	Lang:         LangEs,
+	SourceLang:   "english",
+	Synopsis:     "A fantasy series.",
+	Tone:         "solemn",
+	Formality:    "Address people using the formal register (usted).",
+	Glossary:     Glossary{{Source: "Sacred Kingdom", Target: "Reino Sagrado"}},
+	Speaker:      Character{Name: "Neia Baraja", Gender: "female"},
+	SpeakerLabel: "Neia",
+	Characters:   []Character{{Name: "Neia Baraja", Gender: "female", Description: "squire of the Paladin Order"}},
+	Relationships: []Relationship{
+		{From: "Neia Baraja", To: "Remedios Custodio", Relation: "subordinate of", Formality: "formal"},
+	},
+	Hint: "keep it short",
+}
+
+// NewPrompt parses and validates the system and user templates, the user template must include the line to translate
+func NewPrompt(system, user string) (*Prompt, error) {
+	sysTmpl, err := template.New("system").Option("missingkey=error").Parse(system)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system template: %v", err)
+	}
+	userTmpl, err := template.New("user").Option("missingkey=error").Parse(user)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user template: %v", err)
+	}
+
+	p := &Prompt{system: sysTmpl, user: userTmpl}
+	_, userMsg, err := p.render(validationMsg)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(userMsg, validationMsg.Line) {
+		return nil, fmt.Errorf("invalid user template: it does not contain the line to translate ({{.Line}})")
+	}
+
+	p.hash = hashStrings([]string{system, user})[:12]
+	return p, nil
+}
+
+// LoadPrompt reads the system and user templates from files, the built-in template is used for an empty path
+func LoadPrompt(systemFile, userFile string) (*Prompt, error) {
+	system := defaultSystem
+	user := tmpl
+	if systemFile != "" {
+		data, err := os.ReadFile(systemFile)
+		if err != nil {
+			return nil, err
+		}
+		system = string(data)
+	}
+	if userFile != "" {
+		data, err := os.ReadFile(userFile)
+		if err != nil {
+			return nil, err
+		}
+		user = string(data)
+	}
+	return NewPrompt(system, user)
+}
+
+// Hash identifies the templates, it changes whenever the text of any of them changes
+func (p *Prompt) Hash() string {
+	return p.hash
+}
+
+// render executes both templates with the message data
+func (p *Prompt) render(msg chatMsg) (string, string, error) {
+	var sys, user bytes.Buffer
+	err := p.system.Execute(&sys, msg)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to render system template: %v", err)
+	}
+	err = p.user.Execute(&user, msg)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to render user template: %v", err)
+	}
+	return sys.String(), user.String(), nil
+}
@@ -0,0 +1,109 @@
+package llmtranslate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewPrompt(t *testing.T) {
+	tcs := []struct {
+		name    string
+		system  string
+		user    string
+		wantErr string
+	}{
+		{
+			name:   "valid templates",
+			system: "Translate subtitles from {{.SourceLang}}.{{if .Synopsis}} The show: {{.Synopsis}}{{end}}",
+			user:   "{{range .Glossary}}{{.Source}}={{.Target}} {{end}}Translate into {{.Lang}}: {{.Line}}",
+		},
+		{
+			name:    "syntax error",
+			system:  "Translate {{.Lang",
+			user:    "{{.Line}}",
+			wantErr: "invalid system template",
+		},
+		{
+			name:    "unknown field",
+			system:  "Translate",
+			user:    "{{.Line}} {{.Show}}",
+			wantErr: "can't evaluate field Show",
+		},
+		{
+			name:    "missing line",
+			system:  "Translate",
+			user:    "Translate into {{.Lang}}",
+			wantErr: "does not contain the line to translate",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewPrompt(tc.system, tc.user)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.tmpl")
+	if err := os.WriteFile(path, []byte("Translate into {{.Lang}}: {{.Line}}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadPrompt("", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	system, user, err := p.render(chatMsg{Line: "Hello", Lang: LangEs, Formality: "Keep it formal."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != "Translate into spanish from spain: Hello" {
+		t.Errorf("unexpected user message: %q", user)
+	}
+	if !strings.HasPrefix(system, "You are a professional translator") || !strings.HasSuffix(system, "\nKeep it formal.") {
+		t.Errorf("unexpected system message: %q", system)
+	}
+
+	if p.Hash() == DefaultPrompt().Hash() {
+		t.Error("expected a different hash for a different template")
+	}
+	if DefaultPrompt().Hash() != DefaultPrompt().Hash() {
+		t.Error("expected the same hash for the same template")
+	}
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	c, err := OpenCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("k1", CacheEntry{Source: "Hello", Target: "Hola", Lang: LangEs})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = OpenCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Get("k1")
+	if !ok || got.Target != "Hola" {
+		t.Errorf("expected cached translation, got %v %v", got, ok)
+	}
+	if _, ok := c.Get("k2"); ok {
+		t.Error("expected no entry for an unknown key")
+	}
+}
@@ -0,0 +1,77 @@
+package llmtranslate
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// tokenEncoding is the tiktoken encoding used to count tokens, local models use different tokenizers
+// but cl100k_base is close enough to keep prompts inside the context window
+const tokenEncoding = "cl100k_base"
+
+var (
+	encodingOnce sync.Once
+	encoding     *tiktoken.Tiktoken
+	// loadEncoding is replaced in tests to check the estimation, the encodings embedded in the binary are used
+	// so that counting tokens never downloads them
+	loadEncoding = func() (*tiktoken.Tiktoken, error) {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+		return tiktoken.GetEncoding(tokenEncoding)
+	}
+)
+
+// CountTokens returns the amount of tokens of text, if the tiktoken encoding cannot be loaded
+// the amount is estimated with EstimateTokens
+func CountTokens(text string) int {
+	encodingOnce.Do(func() {
+		enc, err := loadEncoding()
+		if err == nil {
+			encoding = enc
+		}
+	})
+	if encoding == nil {
+		return EstimateTokens(text)
+	}
+	return len(encoding.Encode(text, nil, nil))
+}
+
+// EstimateTokens returns a rough amount of tokens for text, assuming about 4 characters per token
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Usage holds the tokens used by the translations of a run
+type Usage struct {
+	// Requests is the amount of translation requests sent to the model
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	// Shrunk is the amount of requests whose context lines were reduced to fit the context window
+	Shrunk int
+}
+
+// usageCounter accumulates Usage from concurrent translations
+type usageCounter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+func (u *usageCounter) add(prompt, completion int, shrunk bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.usage.Requests++
+	u.usage.PromptTokens += prompt
+	u.usage.CompletionTokens += completion
+	if shrunk {
+		u.usage.Shrunk++
+	}
+}
+
+func (u *usageCounter) get() Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.usage
+}
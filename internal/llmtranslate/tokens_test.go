@@ -0,0 +1,104 @@
+package llmtranslate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// offlineTokens makes CountTokens use the estimation, as if the encoding could not be loaded
+func offlineTokens(t *testing.T) {
+	load := loadEncoding
+	loadEncoding = func() (*tiktoken.Tiktoken, error) { return nil, fmt.Errorf("offline") }
+	encodingOnce, encoding = sync.Once{}, nil
+	t.Cleanup(func() {
+		loadEncoding = load
+		encodingOnce, encoding = sync.Once{}, nil
+	})
+}
+
+func TestCountTokensOffline(t *testing.T) {
+	offlineTokens(t)
+	if got := CountTokens("Where's your report?"); got != EstimateTokens("Where's your report?") {
+		t.Errorf("expected the estimation when the encoding is not available, got %d", got)
+	}
+}
+
+func TestFitContext(t *testing.T) {
+	offlineTokens(t)
+	prev := []string{"first line of context " + strings.Repeat("x", 400), "second line", "third line"}
+	post := []string{"fourth line", "fifth line", "sixth line " + strings.Repeat("x", 400)}
+
+	model := &stubModel{answer: "¿Dónde está tu informe?"}
+	tr := &Translator{client: model, prompt: DefaultPrompt()}
+	withoutContext, err := tr.PromptTokens(nil, nil, "Where's your report?", "", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// room for the short lines but not for the long ones
+	tr.contextLength = withoutContext + replyTokens + 30
+
+	_, err = tr.Translate(context.Background(), prev, post, "Where's your report?", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := model.messages[len(model.messages)-1].Parts[0].(llms.TextContent).Text
+	for _, line := range []string{"second line", "third line", "fourth line", "fifth line"} {
+		if !strings.Contains(sent, line) {
+			t.Errorf("expected %q to be kept in the prompt:\n%s", line, sent)
+		}
+	}
+	if strings.Contains(sent, "first line") || strings.Contains(sent, "sixth line") {
+		t.Errorf("expected the outermost lines to be dropped:\n%s", sent)
+	}
+
+	_, err = tr.Translate(context.Background(), nil, nil, "Where's your report?", LangEs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := tr.Usage()
+	want := Usage{
+		Requests:         2,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: 2 * EstimateTokens("¿Dónde está tu informe?"),
+		Shrunk:           1,
+	}
+	if diff := cmp.Diff(want, usage); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if usage.PromptTokens <= 2*withoutContext {
+		t.Errorf("expected the prompt tokens to include the context, got %d", usage.PromptTokens)
+	}
+}
+
+func TestCountTokensEmbedded(t *testing.T) {
+	encodingOnce, encoding = sync.Once{}, nil
+	t.Cleanup(func() { encodingOnce, encoding = sync.Once{}, nil })
+	// the encoding is embedded, so it is counted exactly without network access
+	if got := CountTokens("Where's your report?"); got != 5 {
+		t.Errorf("expected 5 tokens, got %d", got)
+	}
+	if encoding == nil {
+		t.Error("expected the embedded encoding to be loaded")
+	}
+}
+
+func TestFitsContext(t *testing.T) {
+	tr := &Translator{contextLength: 1000}
+	if !tr.FitsContext(1000 - replyTokens) {
+		t.Error("expected a prompt leaving room for the reply to fit")
+	}
+	// the prompt itself fits but there is no room left for the reply
+	if tr.FitsContext(900) {
+		t.Error("expected a prompt without room for the reply not to fit")
+	}
+	if !(&Translator{}).FitsContext(100000) {
+		t.Error("expected any prompt to fit with an unknown context window")
+	}
+}
@@ -1,44 +1,252 @@
 package llmtranslate
 
 import (
-	"bytes"
 	"context"
-	"github.com/tmc/langchaingo/llms"
-	"text/template"
+	"fmt"
+	"sync"
 
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
 )
 
-// Translator is responsible for connecting to Ollama and translating text
+// Translator translates text with a model served by ollama or by an openai compatible server, see WithBackend
 type Translator struct {
-	client *ollama.LLM
-	temp   float64
+	client   llms.Model
+	model    string
+	temp     float64
+	glossary Glossary
+	prompt   *Prompt
+	// tone and formality are added to the prompt, voices override them for specific speakers
+	tone         string
+	formality    string
+	voices       []Voice
+	sourceLang   string
+	synopsis     string
+	examples     Examples
+	exampleCount int
+	notes        ShowNotes
+	// memory is optional, fuzzy matches of a line are sent to the model after the examples
+	memory     *Memory
+	fuzzyScore float64
+	fuzzyCount int
+	// fuzzy holds the memory matches of the lines of this translator, the prompt of a line is rendered several times
+	fuzzyMu sync.Mutex
+	fuzzy   map[string][]MemoryMatch
+	// info holds the capabilities of the model, the name is empty if the model is not in the registry
+	info ModelInfo
+	// contextLength is the context window in tokens, 0 if unknown, prompts are shrunk to fit in it
+	contextLength int
+	usage         usageCounter
 }
 
+const BackendOllama = "ollama"
+const BackendOpenAI = "openai"
+
 const ModelLlama3 = "llama3"
 const ModelLlama31 = "llama3.1:8b"
 const ModelLlama32 = "llama3.2"
 const ModelPhi35mini = "phi3.5"
 const ModelPhi4 = "phi4:14b"
 const ModelGemma3 = "gemma3:12b"
-const Mistral7b = "mixtral:8x7b"
+const ModelMixtral = "mixtral:8x7b"
+const ModelMistral7b = "mistral:7b"
 const MistralNemo = "mistral-nemo"
+
+// Deprecated: Mistral7b is mixtral:8x7b, use ModelMixtral or ModelMistral7b instead
+const Mistral7b = ModelMixtral
 const defaultUrl = "http://127.0.0.1:11434"
 
-// NewTranslator creates a new Translator instance
-func NewTranslator(model, url string, temp float64) (*Translator, error) {
+// defaultOllamaContext is the context window ollama uses for models that are not in the registry
+const defaultOllamaContext = 2048
+
+// replyTokens is reserved in the context window for the translated line
+const replyTokens = 256
+
+type settings struct {
+	backend      string
+	apiKey       string
+	glossary     Glossary
+	tone         string
+	formality    string
+	voices       []Voice
+	prompt       *Prompt
+	sourceLang   string
+	synopsis     string
+	examples     Examples
+	exampleCount int
+	notes        ShowNotes
+	memory       *Memory
+	fuzzyScore   float64
+	fuzzyCount   int
+}
+
+// Option configures optional settings of the Translator
+type Option func(*settings)
+
+// WithBackend selects the kind of server to connect to, BackendOllama by default
+func WithBackend(backend string) Option {
+	return func(s *settings) {
+		s.backend = backend
+	}
+}
+
+// WithAPIKey sets the key used to authenticate against openai compatible servers
+func WithAPIKey(key string) Option {
+	return func(s *settings) {
+		s.apiKey = key
+	}
+}
+
+// WithGlossary sets terms that must always be translated in the same way
+func WithGlossary(entries Glossary) Option {
+	return func(s *settings) {
+		s.glossary = entries
+	}
+}
 
-	if url == "" {
-		url = defaultUrl
+// WithTone describes the tone of the translation, e.g. "casual, this is a comedy", by default the model
+// is asked to keep the tone of the original
+func WithTone(text string) Option {
+	return func(s *settings) {
+		s.tone = text
 	}
+}
+
+// WithFormality sets the register used to address people: FormalityFormal, FormalityInformal or FormalityAuto
+func WithFormality(f string) Option {
+	return func(s *settings) {
+		s.formality = f
+	}
+}
+
+// WithVoices overrides the formality and tone for the lines of specific characters
+func WithVoices(voices []Voice) Option {
+	return func(s *settings) {
+		s.voices = voices
+	}
+}
+
+// WithPrompt replaces the built-in prompt templates
+func WithPrompt(p *Prompt) Option {
+	return func(s *settings) {
+		s.prompt = p
+	}
+}
 
-	llm, err := ollama.New(ollama.WithModel(model), ollama.WithServerURL(url))
+// WithSourceLanguage sets the language of the original subtitles, available to the templates as .SourceLang
+func WithSourceLanguage(lang string) Option {
+	return func(s *settings) {
+		s.sourceLang = lang
+	}
+}
+
+// WithSynopsis sets a short description of the show, available to the templates as .Synopsis
+func WithSynopsis(text string) Option {
+	return func(s *settings) {
+		s.synopsis = text
+	}
+}
+
+// WithExamples sets reference translations sent to the model as previous chat turns, only examples of the
+// translated language pair are used, if count is bigger than 0 only the examples most similar to each line are sent
+func WithExamples(ex Examples, count int) Option {
+	return func(s *settings) {
+		s.examples = ex
+		s.exampleCount = count
+	}
+}
+
+// WithMemory sets a translation memory: exact matches are returned by MemoryMatch and the fuzzy matches with at
+// least minScore similarity, up to count of them, are sent to the model as examples; 0 uses the defaults
+func WithMemory(m *Memory, minScore float64, count int) Option {
+	return func(s *settings) {
+		s.memory = m
+		s.fuzzyScore = minScore
+		s.fuzzyCount = count
+	}
+}
+
+// WithShowNotes sets the synopsis, characters and relationships of the show, they are added to the system prompt
+func WithShowNotes(n ShowNotes) Option {
+	return func(s *settings) {
+		s.notes = n
+		if n.Synopsis != "" {
+			s.synopsis = n.Synopsis
+		}
+	}
+}
+
+// NewTranslator creates a new Translator instance
+func NewTranslator(model, url string, temp float64, opts ...Option) (*Translator, error) {
+	cfg := settings{backend: BackendOllama}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := ValidFormality(cfg.formality); err != nil {
+		return nil, err
+	}
+	for _, v := range cfg.voices {
+		if err := ValidFormality(v.Formality); err != nil {
+			return nil, fmt.Errorf("voice %s: %v", v.Speaker, err)
+		}
+	}
+
+	var llm llms.Model
+	var err error
+	switch cfg.backend {
+	case BackendOllama, "":
+		if url == "" {
+			url = defaultUrl
+		}
+		ollamaOpts := []ollama.Option{ollama.WithModel(model), ollama.WithServerURL(url)}
+		if info, ok := LookupModel(model); ok {
+			ollamaOpts = append(ollamaOpts, ollama.WithRunnerNumCtx(info.ContextLength))
+		}
+		llm, err = ollama.New(ollamaOpts...)
+	case BackendOpenAI:
+		openaiOpts := []openai.Option{openai.WithModel(model), openai.WithToken(cfg.apiKey)}
+		if url != "" {
+			openaiOpts = append(openaiOpts, openai.WithBaseURL(url))
+		}
+		llm, err = openai.New(openaiOpts...)
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s", cfg.backend)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if cfg.prompt == nil {
+		cfg.prompt = DefaultPrompt()
+	}
 	t := &Translator{
-		client: llm,
-		temp:   temp,
+		client:       llm,
+		model:        model,
+		temp:         temp,
+		glossary:     cfg.glossary,
+		prompt:       cfg.prompt,
+		tone:         cfg.tone,
+		formality:    cfg.formality,
+		voices:       cfg.voices,
+		sourceLang:   cfg.sourceLang,
+		synopsis:     cfg.synopsis,
+		examples:     cfg.examples,
+		exampleCount: cfg.exampleCount,
+		notes:        cfg.notes,
+		memory:       cfg.memory,
+		fuzzyScore:   cfg.fuzzyScore,
+		fuzzyCount:   cfg.fuzzyCount,
+	}
+	if t.fuzzyScore == 0 {
+		t.fuzzyScore = defaultFuzzyScore
+	}
+	if t.fuzzyCount == 0 {
+		t.fuzzyCount = defaultFuzzyCount
+	}
+	t.info, _ = LookupModel(model)
+	t.contextLength = t.info.ContextLength
+	if t.contextLength == 0 && (cfg.backend == BackendOllama || cfg.backend == "") {
+		t.contextLength = defaultOllamaContext
 	}
 	return t, nil
 }
@@ -49,66 +257,335 @@ type chatMsg struct {
 	PostContext []string
 	Line        string
 	Lang        string
+	SourceLang  string
+	Synopsis    string
+	// Tone is a free form description of the tone, empty to keep the one of the original
+	Tone string
+	// Formality is an instruction about the register used to address people, e.g. "use usted"
+	Formality string
+	Glossary  Glossary
+	// Speaker is the character saying the line, the name is empty if unknown
+	Speaker Character
+	// SpeakerLabel is the speaker as found in the subtitles, the same label is used in the context lines
+	SpeakerLabel  string
+	Characters    []Character
+	Relationships []Relationship
+	// Hint is a note about this line only, e.g. from a reviewer
+	Hint string
 }
 
 var tmpl = `Given the subtitle lines as follows:
 {{range .PrevContext}}- {{.}}
 {{end}}
-- {{.Line}}
+- {{if .SpeakerLabel}}{{.SpeakerLabel}}: {{end}}{{.Line}}
 {{range .PostContext}}- {{.}}
 {{end}}
 
 translate the line: >>>  '{{.Line}}' <<< 
-into {{.Lang}}
-
+{{if .SourceLang}}from {{.SourceLang}} {{end}}into {{.Lang}}
+{{if .Speaker.Name}}The line is said by {{.Speaker.Name}}{{if .Speaker.Gender}} ({{.Speaker.Gender}}){{end}}.
+{{end}}{{if .Hint}}Take into account the following note: {{.Hint}}
+{{end}}{{if .Glossary}}
+Always translate the following terms as indicated:
+{{range .Glossary}}- {{.Source}}: {{.Target}}
+{{end}}{{end}}
 Please make sure to only say the translated line, if the line only contains a name or something that cannot be translated. leave it like it is. 
 No babbling or explanation, don't print the context, don't print special chars like " to indicate this is the output.
 `
 
-// FormatMessage formats the message for translation using the Go template engine
+// FormatMessage formats the message for translation using the built-in user template
 func (c *chatMsg) FormatMessage() (string, error) {
-	msg, err := template.New("message").Parse(tmpl)
+	_, user, err := DefaultPrompt().render(*c)
+	return user, err
+}
+
+const LangEs = "spanish from spain"
+
+const systemPromt = `You are a professional translator with deep knowledge of different languages and phrasing.
+your task is to translate movie subtitles from one language to another {{if .Tone}}using the following tone: {{.Tone}}.{{else}}trying to keep the sentiment of the conversation and the tone as close as possible to the original.{{end}} 
+You will be given a context and a line to translate in that context.{{if .Formality}}
+{{.Formality}}{{end}}`
+
+// Translate translates the given text to the specified language
+func (t *Translator) Translate(ctx context.Context, prevContext, postContext []string, translateLine, lang string) (string, error) {
+	return t.TranslateAs(ctx, prevContext, postContext, translateLine, "", lang)
+}
+
+// TranslateAs translates the given text said by speaker to the specified language, the speaker is
+// matched against the characters of the show notes
+func (t *Translator) TranslateAs(ctx context.Context, prevContext, postContext []string, translateLine, speaker, lang string) (string, error) {
+	ex, err := t.Exchange(ctx, prevContext, postContext, translateLine, speaker, lang)
 	if err != nil {
 		return "", err
 	}
+	return ex.Reply, nil
+}
+
+// Exchange is like TranslateAs but it returns the chat sent to the model together with the raw reply
+func (t *Translator) Exchange(ctx context.Context, prevContext, postContext []string, translateLine, speaker, lang string) (Exchange, error) {
+	return t.ExchangeWithHint(ctx, prevContext, postContext, translateLine, speaker, lang, "")
+}
 
-	var buf bytes.Buffer
-	err = msg.Execute(&buf, c)
+// ExchangeWithHint is like Exchange but adds a note to the prompt, e.g. a reviewer asking for a shorter translation,
+// available to the templates as .Hint
+func (t *Translator) ExchangeWithHint(ctx context.Context, prevContext, postContext []string, translateLine, speaker, lang, hint string) (Exchange, error) {
+	ex, content, err := t.render(prevContext, postContext, translateLine, speaker, lang, hint)
 	if err != nil {
-		return "", err
+		return ex, err
 	}
+	return t.generate(ctx, ex, content)
+}
 
-	return buf.String(), nil
+// BackTranslate translates a line from sourceLang into lang with the built-in prompt and without the glossary,
+// voices, examples or memory of the translator, it is used to translate a result back to estimate its quality
+func (t *Translator) BackTranslate(ctx context.Context, line, sourceLang, lang string) (string, error) {
+	system, user, err := DefaultPrompt().render(chatMsg{Line: line, Lang: lang, SourceLang: sourceLang})
+	if err != nil {
+		return "", err
+	}
+	content := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, system),
+		llms.TextParts(llms.ChatMessageTypeHuman, user),
+	}
+	ex := Exchange{Model: t.model, Temperature: t.temp, PromptTokens: countTokens(content)}
+	ex, err = t.generate(ctx, ex, content)
+	if err != nil {
+		return "", err
+	}
+	return ex.Reply, nil
 }
 
-const LangEs = "spanish from spain"
+// generate sends the chat to the model and records the tokens used
+func (t *Translator) generate(ctx context.Context, ex Exchange, content []llms.MessageContent) (Exchange, error) {
+	resp, err := t.client.GenerateContent(ctx, content, llms.WithTemperature(t.temp))
+	if err != nil {
+		return ex, err
+	}
+	ex.Reply = resp.Choices[0].Content
 
-const systemPromt = `You are a professional translator with deep knowledge of different languages and phrasing.
-your task is to translate movie subtitles from one language to another trying to keep the sentiment of the conversation and the tone as close as possible to the original. 
-You will be given a context and a line to translate in that context.`
+	// prefer the amounts reported by the server, they are exact
+	tokens, completion := ex.PromptTokens, 0
+	info := resp.Choices[0].GenerationInfo
+	if n, ok := info["PromptTokens"].(int); ok && n > 0 {
+		tokens = n
+	}
+	if n, ok := info["CompletionTokens"].(int); ok && n > 0 {
+		completion = n
+	} else {
+		completion = CountTokens(ex.Reply)
+	}
+	t.usage.add(tokens, completion, ex.Shrunk)
 
-// Translate translates the given text to the specified language
-func (t *Translator) Translate(ctx context.Context, prevContext, postContext []string, translateLine, lang string) (string, error) {
+	return ex, nil
+}
 
-	msg := chatMsg{
-		PrevContext: prevContext,
-		PostContext: postContext,
-		Line:        translateLine,
-		Lang:        lang,
+// Render returns the chat that would be sent to the model to translate a line, after fitting it in the context window
+func (t *Translator) Render(prevContext, postContext []string, translateLine, speaker, lang string) (Exchange, error) {
+	ex, _, err := t.render(prevContext, postContext, translateLine, speaker, lang, "")
+	return ex, err
+}
+
+func (t *Translator) render(prevContext, postContext []string, translateLine, speaker, lang, hint string) (Exchange, []llms.MessageContent, error) {
+	prevContext, postContext, tokens, shrunk, err := t.fitContext(prevContext, postContext, translateLine, speaker, lang, hint)
+	if err != nil {
+		return Exchange{}, nil, err
 	}
-	parsedMsg, err := msg.FormatMessage()
+	content, err := t.messages(prevContext, postContext, translateLine, speaker, lang, hint)
 	if err != nil {
-		return "", err
+		return Exchange{}, nil, err
+	}
+	ex := Exchange{Model: t.model, Temperature: t.temp, PromptTokens: tokens, Shrunk: shrunk}
+	for _, m := range content {
+		for _, part := range m.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				ex.Messages = append(ex.Messages, Message{Role: string(m.Role), Text: text.Text})
+			}
+		}
+	}
+	return ex, content, nil
+}
+
+// fitContext drops the context lines furthest from the translated line until the prompt fits in the
+// context window, it returns the remaining context, the tokens of the prompt and true if lines were dropped
+func (t *Translator) fitContext(prevContext, postContext []string, translateLine, speaker, lang, hint string) ([]string, []string, int, bool, error) {
+	shrunk := false
+	for {
+		tokens, err := t.promptTokens(prevContext, postContext, translateLine, speaker, lang, hint)
+		if err != nil {
+			return nil, nil, 0, false, err
+		}
+		// without context there is nothing left to drop, the server truncates the prompt
+		if t.FitsContext(tokens) || len(prevContext)+len(postContext) == 0 {
+			return prevContext, postContext, tokens, shrunk, nil
+		}
+		shrunk = true
+		if len(prevContext) >= len(postContext) {
+			prevContext = prevContext[1:]
+		} else {
+			postContext = postContext[:len(postContext)-1]
+		}
+	}
+}
+
+// messages creates the chat sent to the model: the system prompt, the examples as previous turns and the line
+func (t *Translator) messages(prevContext, postContext []string, translateLine, speaker, lang, hint string) ([]llms.MessageContent, error) {
+	system, parsedMsg, err := t.prompt.render(t.message(prevContext, postContext, translateLine, speaker, lang, hint))
+	if err != nil {
+		return nil, err
 	}
 
 	content := []llms.MessageContent{
-		llms.TextParts(llms.ChatMessageTypeSystem, systemPromt),
-		llms.TextParts(llms.ChatMessageTypeHuman, parsedMsg),
+		llms.TextParts(llms.ChatMessageTypeSystem, system),
 	}
-	resp, err := t.client.GenerateContent(ctx, content, llms.WithTemperature(t.temp))
+	for _, e := range t.examples.forPair(t.sourceLang, lang).mostSimilar(translateLine, t.exampleCount) {
+		_, exampleMsg, err := t.prompt.render(t.message(e.Context, nil, e.Source, "", lang, ""))
+		if err != nil {
+			return nil, err
+		}
+		content = append(content,
+			llms.TextParts(llms.ChatMessageTypeHuman, exampleMsg),
+			llms.TextParts(llms.ChatMessageTypeAI, e.Target),
+		)
+	}
+	for _, m := range t.fuzzyMatches(translateLine, lang) {
+		_, memoryMsg, err := t.prompt.render(t.message(nil, nil, m.Source, "", lang, ""))
+		if err != nil {
+			return nil, err
+		}
+		content = append(content,
+			llms.TextParts(llms.ChatMessageTypeHuman, memoryMsg),
+			llms.TextParts(llms.ChatMessageTypeAI, m.Target),
+		)
+	}
+	return append(content, llms.TextParts(llms.ChatMessageTypeHuman, parsedMsg)), nil
+}
+
+// fuzzyMatches returns the fuzzy matches of a line in the translation memory, they are searched once per line
+func (t *Translator) fuzzyMatches(line, lang string) []MemoryMatch {
+	if t.memory.Len() == 0 {
+		return nil
+	}
+	key := lang + "\x1f" + line
+	t.fuzzyMu.Lock()
+	out, ok := t.fuzzy[key]
+	t.fuzzyMu.Unlock()
+	if ok {
+		return out
+	}
+	out = t.memory.Fuzzy(line, t.sourceLang, lang, t.fuzzyScore, t.fuzzyCount)
+	t.fuzzyMu.Lock()
+	if t.fuzzy == nil {
+		t.fuzzy = map[string][]MemoryMatch{}
+	}
+	t.fuzzy[key] = out
+	t.fuzzyMu.Unlock()
+	return out
+}
+
+// message creates the template data for a line
+func (t *Translator) message(prevContext, postContext []string, line, speaker, lang, hint string) chatMsg {
+	character := t.notes.character(speaker)
+	formality, tone := t.voice(speaker, character)
+	return chatMsg{
+		PrevContext:   prevContext,
+		PostContext:   postContext,
+		Line:          line,
+		Lang:          lang,
+		SourceLang:    t.sourceLang,
+		Synopsis:      t.synopsis,
+		Tone:          tone,
+		Formality:     formalityInstruction(formality, lang),
+		Glossary:      t.glossary.matching(line),
+		Speaker:       character,
+		SpeakerLabel:  speaker,
+		Characters:    t.notes.Characters,
+		Relationships: t.notes.Relationships,
+		Hint:          hint,
+	}
+}
+
+// Model returns the name of the model used for translations
+func (t *Translator) Model() string {
+	return t.model
+}
+
+// SourceLanguage returns the language of the original subtitles, empty if it was not set
+func (t *Translator) SourceLanguage() string {
+	return t.sourceLang
+}
+
+// PromptTokens counts the tokens of the chat sent to translate a line before fitting it in the context window
+func (t *Translator) PromptTokens(prevContext, postContext []string, translateLine, speaker, lang string) (int, error) {
+	return t.promptTokens(prevContext, postContext, translateLine, speaker, lang, "")
+}
+
+func (t *Translator) promptTokens(prevContext, postContext []string, translateLine, speaker, lang, hint string) (int, error) {
+	content, err := t.messages(prevContext, postContext, translateLine, speaker, lang, hint)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
+	return countTokens(content), nil
+}
 
-	return resp.Choices[0].Content, nil
+// countTokens returns the tokens of the text of all the messages
+func countTokens(content []llms.MessageContent) int {
+	tokens := 0
+	for _, m := range content {
+		for _, part := range m.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				tokens += CountTokens(text.Text)
+			}
+		}
+	}
+	return tokens
+}
+
+// ModelInfo returns the capabilities of the model, false if the model is not in the registry
+func (t *Translator) ModelInfo() (ModelInfo, bool) {
+	return t.info, t.info.Name != ""
+}
+
+// Usage returns the tokens used by the translations so far, it is safe to call while translating
+func (t *Translator) Usage() Usage {
+	return t.usage.get()
+}
+
+// ContextLength returns the context window in tokens that prompts are fitted in, 0 if unknown
+func (t *Translator) ContextLength() int {
+	return t.contextLength
+}
+
+// FitsContext returns true if a prompt of tokens leaves room for the reply in the context window, prompts that
+// do not fit have their context lines dropped
+func (t *Translator) FitsContext(tokens int) bool {
+	return t.contextLength == 0 || tokens+replyTokens <= t.contextLength
+}
+
+// PromptHash identifies the prompt templates used by the translator
+func (t *Translator) PromptHash() string {
+	return t.prompt.Hash()
+}
+
+// MemoryMatch returns the translation of a line found as is in the translation memory
+func (t *Translator) MemoryMatch(line, lang string) (string, bool) {
+	return t.memory.Exact(line, t.sourceLang, lang)
+}
+
+// CacheKey identifies a translation request, translations with the same key are expected to be
+// interchangeable, the key is derived from the model, the temperature and the full chat sent to the model
+func (t *Translator) CacheKey(prevContext, postContext []string, translateLine, speaker, lang string) (string, error) {
+	content, err := t.messages(prevContext, postContext, translateLine, speaker, lang, "")
+	if err != nil {
+		return "", err
+	}
+	parts := []string{t.model, fmt.Sprintf("%g", t.temp)}
+	for _, m := range content {
+		parts = append(parts, string(m.Role))
+		for _, part := range m.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				parts = append(parts, text.Text)
+			}
+		}
+	}
+	return hashStrings(parts), nil
 }
@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/tmc/langchaingo/llms"
 )
 
 func TestTranslate(t *testing.T) {
@@ -104,3 +106,41 @@ func TestTranslate(t *testing.T) {
 		})
 	}
 }
+
+func TestBackTranslate(t *testing.T) {
+	model := &stubModel{answer: "Where's your report?"}
+	tr := &Translator{
+		client:     model,
+		prompt:     DefaultPrompt(),
+		sourceLang: "English",
+		formality:  FormalityFormal,
+		glossary:   Glossary{{Source: "report", Target: "informe"}},
+		examples:   Examples{{TargetLang: "English", Source: "Hola", Target: "Hello"}},
+		memory:     testMemory(t),
+		fuzzyScore: defaultFuzzyScore,
+		fuzzyCount: defaultFuzzyCount,
+	}
+
+	got, err := tr.BackTranslate(context.Background(), "¿Dónde está tu informe?", "Spanish (Spain)", "English")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != model.answer {
+		t.Errorf("unexpected translation %q", got)
+	}
+	// only the system prompt and the line, without the settings of the forward translation
+	if len(model.messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(model.messages))
+	}
+	system := model.messages[0].Parts[0].(llms.TextContent).Text
+	user := model.messages[1].Parts[0].(llms.TextContent).Text
+	if !strings.Contains(user, "from Spanish (Spain) into English") {
+		t.Errorf("unexpected languages in the prompt:\n%s", user)
+	}
+	if strings.Contains(user, "Always translate") || strings.Contains(system, "formal") {
+		t.Errorf("unexpected forward settings in the prompt:\n%s\n%s", system, user)
+	}
+	if tr.Usage().PromptTokens == 0 {
+		t.Error("expected the tokens to be counted")
+	}
+}
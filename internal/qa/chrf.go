@@ -0,0 +1,79 @@
+package qa
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	chrfOrder = 6
+	chrfBeta  = 2
+)
+
+// ChrF computes the character n-gram F-score of hypothesis against reference, in a range from 0 to 100.
+// Whitespace is ignored and the comparison is case-insensitive; the precision and recall are averaged over
+// n-grams of length 1 to 6 and combined giving recall twice the weight of precision.
+func ChrF(hypothesis, reference string) float64 {
+	hyp := normalize(hypothesis)
+	ref := normalize(reference)
+	if len(hyp) == 0 && len(ref) == 0 {
+		return 100
+	}
+	if len(hyp) == 0 || len(ref) == 0 {
+		return 0
+	}
+
+	var precision, recall float64
+	orders := 0
+	for n := 1; n <= chrfOrder; n++ {
+		hypGrams := ngrams(hyp, n)
+		refGrams := ngrams(ref, n)
+		hypTotal, refTotal := total(hypGrams), total(refGrams)
+		if hypTotal == 0 || refTotal == 0 {
+			continue
+		}
+		matches := 0
+		for g, c := range hypGrams {
+			matches += min(c, refGrams[g])
+		}
+		precision += float64(matches) / float64(hypTotal)
+		recall += float64(matches) / float64(refTotal)
+		orders++
+	}
+	if orders == 0 {
+		return 0
+	}
+	precision /= float64(orders)
+	recall /= float64(orders)
+	if precision == 0 && recall == 0 {
+		return 0
+	}
+	beta2 := float64(chrfBeta * chrfBeta)
+	return 100 * (1 + beta2) * precision * recall / (beta2*precision + recall)
+}
+
+func normalize(s string) []rune {
+	out := []rune{}
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsSpace(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func ngrams(text []rune, n int) map[string]int {
+	grams := map[string]int{}
+	for i := 0; i+n <= len(text); i++ {
+		grams[string(text[i:i+n])]++
+	}
+	return grams
+}
+
+func total(grams map[string]int) int {
+	t := 0
+	for _, c := range grams {
+		t += c
+	}
+	return t
+}
@@ -0,0 +1,146 @@
+package qa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/andresbott/substrans/internal/subsedit"
+)
+
+// ModeBackTranslate estimates the quality by translating the result back to the source language
+const ModeBackTranslate = "backtranslate"
+
+// Translator translates a single line from sourceLang into lang without the settings of the forward translation,
+// it is implemented by llmtranslate.Translator
+type Translator interface {
+	BackTranslate(ctx context.Context, line, sourceLang, lang string) (string, error)
+}
+
+// Entry holds the quality estimation of a single translated item
+type Entry struct {
+	Index           int     `json:"index"`
+	Start           string  `json:"start"`
+	End             string  `json:"end"`
+	Source          string  `json:"source"`
+	Target          string  `json:"target"`
+	BackTranslation string  `json:"back_translation"`
+	Score           float64 `json:"score"`
+	Flagged         bool    `json:"flagged"`
+}
+
+// BackTranslate translates the target text of every result from lang back into sourceLang and scores it with
+// chrF against the original text, a missing translation of a non-empty item scores 0
+func BackTranslate(ctx context.Context, tr Translator, results []subsedit.Result, lang, sourceLang string) ([]Entry, error) {
+	entries := make([]Entry, 0, len(results))
+	for _, r := range results {
+		e := Entry{
+			Index:  r.Index,
+			Start:  subsedit.FormatTime(r.StartAt),
+			End:    subsedit.FormatTime(r.EndAt),
+			Source: r.Source,
+			Target: r.Target,
+		}
+		target := strings.TrimSpace(strings.ReplaceAll(r.Target, "\n", " "))
+		if target == "" {
+			if strings.TrimSpace(r.Source) == "" {
+				e.Score = 100
+			}
+			entries = append(entries, e)
+			continue
+		}
+
+		back, err := tr.BackTranslate(ctx, target, lang, sourceLang)
+		if err != nil {
+			return nil, fmt.Errorf("error back-translating item %d: %w", r.Index, err)
+		}
+		e.BackTranslation = back
+		e.Score = ChrF(back, strings.ReplaceAll(r.Source, "\n", " "))
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Flag marks the given ratio of entries with the lowest score as flagged, at least one entry is flagged
+// if there are any entries and the ratio is positive.
+func Flag(entries []Entry, ratio float64) {
+	if len(entries) == 0 || ratio <= 0 {
+		return
+	}
+	n := int(math.Ceil(float64(len(entries)) * ratio))
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	idx := make([]int, len(entries))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return entries[idx[i]].Score < entries[idx[j]].Score
+	})
+	for _, i := range idx[:n] {
+		entries[i].Flagged = true
+	}
+}
+
+// FlagAmong is like Flag but ranks the entries together with the scores of other items, e.g. the ones kept from a
+// previous report when only some items were translated again; only the entries are marked
+func FlagAmong(entries []Entry, others []float64, ratio float64) {
+	// the other items come first so that they are flagged on equal scores
+	all := make([]Entry, 0, len(others)+len(entries))
+	for _, score := range others {
+		all = append(all, Entry{Score: score})
+	}
+	all = append(all, entries...)
+	Flag(all, ratio)
+	for i := range entries {
+		entries[i].Flagged = all[len(others)+i].Flagged
+	}
+}
+
+// Report is the result of a quality estimation pass
+type Report struct {
+	Mode      string  `json:"mode"`
+	Language  string  `json:"language"`
+	Items     int     `json:"items"`
+	MeanScore float64 `json:"mean_score"`
+	Flagged   []Entry `json:"flagged"`
+}
+
+// NewReport creates a report with the flagged entries ordered from the worst score
+func NewReport(mode, sourceLang string, entries []Entry) Report {
+	r := Report{
+		Mode:     mode,
+		Language: sourceLang,
+		Items:    len(entries),
+		Flagged:  []Entry{},
+	}
+	sum := 0.0
+	for _, e := range entries {
+		sum += e.Score
+		if e.Flagged {
+			r.Flagged = append(r.Flagged, e)
+		}
+	}
+	if len(entries) > 0 {
+		r.MeanScore = sum / float64(len(entries))
+	}
+	sort.SliceStable(r.Flagged, func(i, j int) bool {
+		return r.Flagged[i].Score < r.Flagged[j].Score
+	})
+	return r
+}
+
+// Write stores the report as indented json
+func (r Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
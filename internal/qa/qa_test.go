@@ -0,0 +1,148 @@
+package qa
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/andresbott/substrans/internal/subsedit"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChrF(t *testing.T) {
+	tcs := []struct {
+		name       string
+		hypothesis string
+		reference  string
+		min        float64
+		max        float64
+	}{
+		{
+			name:       "identical text",
+			hypothesis: "My thoughts were elsewhere.",
+			reference:  "My thoughts were elsewhere.",
+			min:        100,
+			max:        100,
+		},
+		{
+			name:       "case and spaces are ignored",
+			hypothesis: "my thoughts  were elsewhere.",
+			reference:  "My thoughts were elsewhere.",
+			min:        100,
+			max:        100,
+		},
+		{
+			name:       "paraphrase scores high",
+			hypothesis: "My thoughts were somewhere else.",
+			reference:  "My thoughts were elsewhere.",
+			min:        60,
+			max:        90,
+		},
+		{
+			name:       "unrelated text scores low",
+			hypothesis: "Meteor fall!",
+			reference:  "My thoughts were elsewhere.",
+			min:        0,
+			max:        20,
+		},
+		{
+			name:       "empty hypothesis",
+			hypothesis: "",
+			reference:  "My thoughts were elsewhere.",
+			min:        0,
+			max:        0,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ChrF(tc.hypothesis, tc.reference)
+			if got < tc.min || got > tc.max {
+				t.Errorf("ChrF() = %.2f, want between %.2f and %.2f", got, tc.min, tc.max)
+			}
+		})
+	}
+}
+
+type dictTranslator map[string]string
+
+func (d dictTranslator) BackTranslate(_ context.Context, line, _, _ string) (string, error) {
+	return d[line], nil
+}
+
+func TestBackTranslateAndFlag(t *testing.T) {
+	results := []subsedit.Result{
+		{Index: 0, Source: "Where's your report?", Target: "¿Dónde está tu informe?"},
+		{Index: 1, Source: "My apologies,\nSergeant Baraja.", Target: "Mis disculpas,\nsargento Baraja."},
+		{Index: 2, Source: "My thoughts were elsewhere.", Target: "Meteoro"},
+		{Index: 3, Source: "", Target: ""},
+	}
+	tr := dictTranslator{
+		"¿Dónde está tu informe?":         "Where is your report?",
+		"Mis disculpas, sargento Baraja.": "My apologies, Sergeant Baraja.",
+		"Meteoro":                         "Meteor",
+	}
+
+	entries, err := BackTranslate(context.Background(), tr, results, "spanish", "english")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Flag(entries, 0.25)
+
+	report := NewReport(ModeBackTranslate, "english", entries)
+	if len(report.Flagged) != 1 {
+		t.Fatalf("expected 1 flagged item, got %d", len(report.Flagged))
+	}
+	want := Entry{
+		Index:           2,
+		Start:           "00:00:00.000",
+		End:             "00:00:00.000",
+		Source:          "My thoughts were elsewhere.",
+		Target:          "Meteoro",
+		BackTranslation: "Meteor",
+		Flagged:         true,
+	}
+	got := report.Flagged[0]
+	got.Score = 0
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if math.Round(entries[1].Score) != 100 {
+		t.Errorf("expected a perfect score for item 1, got %.2f", entries[1].Score)
+	}
+}
+
+func TestFlagAmong(t *testing.T) {
+	// the item translated again is better than the worst items kept from the previous run
+	entries := []Entry{{Index: 3, Score: 60}, {Index: 7, Score: 90}}
+	FlagAmong(entries, []float64{20, 40, 80, 85, 95, 95, 99, 99}, 0.2)
+	if entries[0].Flagged || entries[1].Flagged {
+		t.Errorf("expected no entries to be flagged, got %+v", entries)
+	}
+
+	entries = []Entry{{Index: 3, Score: 10}, {Index: 7, Score: 90}}
+	FlagAmong(entries, []float64{20, 40, 80, 85, 95, 95, 99, 99}, 0.2)
+	if !entries[0].Flagged || entries[1].Flagged {
+		t.Errorf("expected only the worst entry to be flagged, got %+v", entries)
+	}
+}
+
+func TestBackTranslateMissing(t *testing.T) {
+	results := []subsedit.Result{
+		{Index: 0, Source: "Where's your report?", Target: "¿Dónde está tu informe?"},
+		{Index: 1, Source: "My thoughts were elsewhere.", Target: " \n"},
+	}
+	tr := dictTranslator{"¿Dónde está tu informe?": "Where's your report?"}
+
+	entries, err := BackTranslate(context.Background(), tr, results, "spanish", "english")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[1].Score != 0 {
+		t.Errorf("expected a missing translation to score 0, got %.2f", entries[1].Score)
+	}
+	Flag(entries, 0.1)
+	if !entries[1].Flagged || entries[0].Flagged {
+		t.Errorf("expected only the missing translation to be flagged: %+v", entries)
+	}
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+)
+
+var htmlTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"lines": func(s string) []string { return strings.Split(s, "\n") },
+	"score": func(f *float64) string { return fmt.Sprintf("%.1f", *f) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Input}} - {{.Language}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; position: sticky; top: 0; }
+td.meta { color: #777; white-space: nowrap; font-size: 0.85em; }
+tr.warn { background: #fff4e0; }
+ul { margin: 0; padding-left: 1.2em; color: #b35c00; }
+</style>
+</head>
+<body>
+<h1>{{.Input}}</h1>
+<p>Language: {{.Language}} &middot; Model: {{.Model}}{{if .Prompt}} &middot; Prompt: {{.Prompt}}{{end}} &middot; Items: {{len .Items}} &middot; Tokens: {{.PromptTokens}} prompt, {{.CompletionTokens}} completion &middot; Started: {{.StartedAt.Format "2006-01-02 15:04"}}</p>
+<table>
+<tr><th>#</th><th>Time</th><th>Source</th><th>Translation</th><th>Notes</th></tr>
+{{range .Items}}<tr{{if .Warnings}} class="warn"{{end}}>
+<td class="meta">{{.Index}}</td>
+<td class="meta">{{.Start}}<br>{{.End}}</td>
+<td>{{range $i, $l := lines .Source}}{{if $i}}<br>{{end}}{{$l}}{{end}}</td>
+<td>{{range $i, $l := lines .Target}}{{if $i}}<br>{{end}}{{$l}}{{end}}</td>
+<td class="meta">{{if .QAScore}}score {{score .QAScore}}<br>{{end}}{{if .Retries}}retries {{.Retries}}<br>{{end}}{{if .CacheHit}}cached<br>{{end}}{{if .MemoryHit}}memory<br>{{end}}{{if .Warnings}}<ul>{{range .Warnings}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML stores a standalone html page showing source and translation side by side
+func (r Report) WriteHTML(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return htmlTmpl.Execute(f, r)
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/andresbott/substrans/internal/subsedit"
+)
+
+// Report describes the outcome of a translation run
+type Report struct {
+	Input    string `json:"input"`
+	Output   string `json:"output"`
+	Language string `json:"language"`
+	// SourceLanguage is the language of the input, either given or detected
+	SourceLanguage string `json:"source_language,omitempty"`
+	Model          string `json:"model"`
+	Profile        string `json:"profile,omitempty"`
+	// Prompt is the hash of the prompt templates, it changes whenever the prompt is modified
+	Prompt     string    `json:"prompt,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// PromptTokens and CompletionTokens are the tokens sent to and generated by the model during the run
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	// ContextShrunk is the amount of requests whose context lines were reduced to fit the context window
+	ContextShrunk int    `json:"context_shrunk,omitempty"`
+	Items         []Item `json:"items"`
+}
+
+// Item describes the translation of a single subtitle item
+type Item struct {
+	Index      int      `json:"index"`
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Source     string   `json:"source"`
+	Target     string   `json:"target"`
+	DurationMs int64    `json:"duration_ms"`
+	Retries    int      `json:"retries"`
+	Warnings   []string `json:"warnings"`
+	// CacheHit is true if the translation was not generated but taken from a cache
+	CacheHit bool `json:"cache_hit"`
+	// MemoryHit is true if the translation is an exact match of the translation memory
+	MemoryHit bool `json:"memory_hit,omitempty"`
+	// QAScore is the quality estimation score from 0 to 100, only present if a quality pass was run
+	QAScore *float64 `json:"qa_score,omitempty"`
+}
+
+// Notes holds the information about an item that is known only to the translator
+type Notes struct {
+	Retries   int
+	Warnings  []string
+	CacheHit  bool
+	MemoryHit bool
+}
+
+// New creates a report out of the editor results, notes are matched to results by index
+func New(results []subsedit.Result, notes map[int]Notes) Report {
+	r := Report{Items: make([]Item, 0, len(results))}
+	for _, res := range results {
+		n := notes[res.Index]
+		warnings := n.Warnings
+		if warnings == nil {
+			warnings = []string{}
+		}
+		r.Items = append(r.Items, Item{
+			Index:      res.Index,
+			Start:      subsedit.FormatTime(res.StartAt),
+			End:        subsedit.FormatTime(res.EndAt),
+			Source:     res.Source,
+			Target:     res.Target,
+			DurationMs: res.Duration.Milliseconds(),
+			Retries:    n.Retries,
+			Warnings:   warnings,
+			CacheHit:   n.CacheHit,
+			MemoryHit:  n.MemoryHit,
+		})
+	}
+	return r
+}
+
+// Flagged returns the items that have at least one warning
+func (r Report) Flagged() []Item {
+	out := []Item{}
+	for _, item := range r.Items {
+		if len(item.Warnings) > 0 {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Merge adds the items of a previous report that are not part of this one, e.g. to update a report after
+// translating some of its items again
+func (r *Report) Merge(previous Report) {
+	seen := map[int]bool{}
+	for _, item := range r.Items {
+		seen[item.Index] = true
+	}
+	for _, item := range previous.Items {
+		if !seen[item.Index] {
+			r.Items = append(r.Items, item)
+		}
+	}
+	sort.Slice(r.Items, func(i, j int) bool {
+		return r.Items[i].Index < r.Items[j].Index
+	})
+}
+
+// Write stores the report as indented json
+func (r Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load reads a report previously stored with Write
+func Load(path string) (Report, error) {
+	r := Report{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		return r, fmt.Errorf("unable to parse report %s: %w", path, err)
+	}
+	return r, nil
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andresbott/substrans/internal/subsedit"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReport(t *testing.T) {
+	results := []subsedit.Result{
+		{
+			Index:    0,
+			StartAt:  3*time.Minute + 21600*time.Millisecond,
+			EndAt:    3*time.Minute + 27270*time.Millisecond,
+			Source:   "The Roble Sacred Kingdom, lying on a peninsula\nto the southwest of the Re-Estize Kingdom.",
+			Target:   "El Reino Sagrado de Roble, situado en una península\nal suroeste del Reino de Re-Estize.",
+			Duration: 1500 * time.Millisecond,
+		},
+		{
+			Index:  1,
+			Source: "Where's your report?",
+			Target: "Where's your report?",
+		},
+	}
+	notes := map[int]Notes{
+		1: {Retries: 2, Warnings: []string{"translation is identical to the source"}},
+	}
+
+	rep := New(results, notes)
+	rep.Input = "overlord.ass"
+	rep.Language = "spanish"
+
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "report.json")
+	if err := rep.Write(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(rep, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	wantItem := Item{
+		Index:      0,
+		Start:      "00:03:21.600",
+		End:        "00:03:27.270",
+		Source:     results[0].Source,
+		Target:     results[0].Target,
+		DurationMs: 1500,
+		Warnings:   []string{},
+	}
+	if diff := cmp.Diff(wantItem, got.Items[0]); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if len(got.Flagged()) != 1 || got.Flagged()[0].Index != 1 {
+		t.Errorf("expected item 1 to be flagged, got %v", got.Flagged())
+	}
+
+	htmlPath := filepath.Join(dir, "report.html")
+	if err := rep.WriteHTML(htmlPath); err != nil {
+		t.Fatal(err)
+	}
+	html, err := os.ReadFile(htmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"El Reino Sagrado de Roble, situado en una península<br>al suroeste",
+		"Where&#39;s your report?",
+		"<li>translation is identical to the source</li>",
+	} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("html report does not contain %q", want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	previous := Report{Items: []Item{
+		{Index: 0, Target: "uno"},
+		{Index: 1, Target: "two", Warnings: []string{"not translated"}},
+		{Index: 2, Target: "tres"},
+	}}
+	rep := Report{Items: []Item{{Index: 1, Target: "dos", Warnings: []string{}}}}
+	rep.Merge(previous)
+
+	targets := []string{}
+	for _, item := range rep.Items {
+		targets = append(targets, item.Target)
+	}
+	if diff := cmp.Diff([]string{"uno", "dos", "tres"}, targets); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if len(rep.Flagged()) != 0 {
+		t.Errorf("expected the warnings of the translated item to be replaced")
+	}
+}
@@ -0,0 +1,230 @@
+// Package server exposes the translation of subtitle files as a REST api backed by a job queue
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/andresbott/substrans/internal/jobs"
+	"github.com/andresbott/substrans/internal/langtag"
+	"github.com/andresbott/substrans/internal/subsedit"
+)
+
+// maxUpload is the largest subtitle file accepted
+const maxUpload = 10 << 20
+
+// Request describes the translation of an uploaded file
+type Request struct {
+	// Input is the path of the uploaded file and Output the path where the translation must be written
+	Input          string
+	Output         string
+	Language       string
+	SourceLanguage string
+	Model          string
+	Profile        string
+}
+
+// TranslateFunc translates the file of the request, progress is reported for every translated item
+type TranslateFunc func(ctx context.Context, req Request, progress jobs.ProgressFunc) error
+
+// Server handles the http requests to create translation jobs, poll them and download the results
+type Server struct {
+	queue     *jobs.Queue
+	dir       string
+	translate TranslateFunc
+	log       *slog.Logger
+
+	mu sync.Mutex
+	// outputs are the translated files by job id
+	outputs map[string]string
+}
+
+// New creates a server storing the uploaded and translated files in dir, the files of a job are deleted
+// when the queue removes it
+func New(queue *jobs.Queue, dir string, fn TranslateFunc, log *slog.Logger) *Server {
+	s := &Server{
+		queue:     queue,
+		dir:       dir,
+		translate: fn,
+		log:       log,
+		outputs:   map[string]string{},
+	}
+	queue.OnRemove(s.cleanup)
+	return s
+}
+
+// Handler returns the http handler of the api
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.create)
+	mux.HandleFunc("GET /jobs", s.list)
+	mux.HandleFunc("GET /jobs/{id}", s.get)
+	mux.HandleFunc("GET /jobs/{id}/result", s.result)
+	mux.HandleFunc("DELETE /jobs/{id}", s.remove)
+	return mux
+}
+
+// create stores the uploaded file and queues its translation, the form fields are
+// file, language and the optional source_language, model and profile
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUpload)
+	err := r.ParseMultipartForm(maxUpload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid upload: %v", err))
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing subtitle file: %v", err))
+		return
+	}
+	defer file.Close()
+
+	name := filepath.Base(header.Filename)
+	if !subsedit.IsSubtitle(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported subtitle format %q", filepath.Ext(name)))
+		return
+	}
+	tag, err := langtag.Parse(r.FormValue("language"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid target language: %v", err))
+		return
+	}
+
+	dir, err := os.MkdirTemp(s.dir, "job-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("unable to store upload: %v", err))
+		return
+	}
+	req := Request{
+		Input:          filepath.Join(dir, name),
+		Output:         filepath.Join(dir, strings.TrimSuffix(name, filepath.Ext(name))+"."+tag.String()+filepath.Ext(name)),
+		Language:       tag.String(),
+		SourceLanguage: r.FormValue("source_language"),
+		Model:          r.FormValue("model"),
+		Profile:        r.FormValue("profile"),
+	}
+	err = saveUpload(file, req.Input)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the lock is held until the output is stored so that a job removed right after finishing finds it
+	s.mu.Lock()
+	job, err := s.queue.Submit(func(ctx context.Context, progress jobs.ProgressFunc) error {
+		return s.translate(ctx, req, progress)
+	})
+	if err == nil {
+		s.outputs[job.ID] = req.Output
+	}
+	s.mu.Unlock()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		status := http.StatusInternalServerError
+		if errors.Is(err, jobs.ErrQueueFull) || errors.Is(err, jobs.ErrClosed) {
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, err)
+		return
+	}
+	s.log.Info("job queued", "id", job.ID, "file", name, "language", req.Language)
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.queue.List())
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.queue.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// result sends the translated file of a finished job
+func (s *Server) result(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.queue.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+	if job.Status != jobs.StatusDone {
+		writeError(w, http.StatusConflict, fmt.Errorf("job is %s", job.Status))
+		return
+	}
+	s.mu.Lock()
+	output := s.outputs[job.ID]
+	s.mu.Unlock()
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(output)))
+	http.ServeFile(w, r, output)
+}
+
+// remove deletes a finished job and its files
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	err := s.queue.Remove(r.PathValue("id"))
+	if errors.Is(err, jobs.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cleanup deletes the directory holding the upload and the translation of a job
+func (s *Server) cleanup(id string) {
+	s.mu.Lock()
+	output, ok := s.outputs[id]
+	delete(s.outputs, id)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	err := os.RemoveAll(filepath.Dir(output))
+	if err != nil {
+		s.log.Warn("unable to delete the files of a job", "id", id, "err", err)
+		return
+	}
+	s.log.Debug("job removed", "id", id)
+}
+
+func saveUpload(file io.Reader, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to store upload: %v", err)
+	}
+	_, err = io.Copy(out, file)
+	if err != nil {
+		_ = out.Close()
+		return fmt.Errorf("unable to store upload: %v", err)
+	}
+	return out.Close()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
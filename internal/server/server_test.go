@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andresbott/substrans/internal/jobs"
+)
+
+const testSrt = "1\n00:00:01,000 --> 00:00:02,000\nHello\n"
+
+func silentLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+}
+
+// upload creates a multipart request with the file and form fields
+func upload(t *testing.T, name, content string, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if name != "" {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/jobs", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func decodeJob(t *testing.T, rec *httptest.ResponseRecorder) jobs.Job {
+	t.Helper()
+	job := jobs.Job{}
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestServer(t *testing.T) {
+	queue := jobs.NewQueue(1, 1)
+	defer queue.Close()
+
+	var got Request
+	release := make(chan struct{})
+	fn := func(ctx context.Context, req Request, progress jobs.ProgressFunc) error {
+		got = req
+		<-release
+		in, err := os.ReadFile(req.Input)
+		if err != nil {
+			return err
+		}
+		progress(1, 1, 0)
+		if req.Model == "broken" {
+			return errors.New("model not found")
+		}
+		return os.WriteFile(req.Output, []byte(strings.ReplaceAll(string(in), "Hello", "Hola")), 0644)
+	}
+	handler := New(queue, t.TempDir(), fn, silentLogger()).Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, upload(t, "episode.srt", testSrt, map[string]string{"language": "es", "model": "llama3.1:8b"}))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	job := decodeJob(t, rec)
+	if job.Status != jobs.StatusQueued || rec.Header().Get("Location") != "/jobs/"+job.ID {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	// the result is not available until the job is done
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID+"/result", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected conflict for an unfinished job, got %d", rec.Code)
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Status != jobs.StatusDone && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID, nil))
+		job = decodeJob(t, rec)
+	}
+	if job.Status != jobs.StatusDone || job.Done != 1 || job.Total != 1 {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if got.Language != "es" || got.Model != "llama3.1:8b" || !strings.HasSuffix(got.Output, "episode.es.srt") {
+		t.Errorf("unexpected request: %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID+"/result", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Hola") {
+		t.Errorf("unexpected result %d: %s", rec.Code, rec.Body.String())
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="episode.es.srt"` {
+		t.Errorf("unexpected content disposition: %s", cd)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	list := []jobs.Job{}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list) != 1 {
+		t.Errorf("unexpected list: %v %+v", err, list)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected not found, got %d", rec.Code)
+	}
+}
+
+func TestServerDelete(t *testing.T) {
+	queue := jobs.NewQueue(1, 1)
+	defer queue.Close()
+	fn := func(ctx context.Context, req Request, progress jobs.ProgressFunc) error {
+		return os.WriteFile(req.Output, []byte(testSrt), 0644)
+	}
+	dir := t.TempDir()
+	handler := New(queue, dir, fn, silentLogger()).Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, upload(t, "episode.srt", testSrt, map[string]string{"language": "es"}))
+	job := decodeJob(t, rec)
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Status != jobs.StatusDone && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		job, _ = queue.Get(job.ID)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/jobs/"+job.ID, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected the job files to be deleted, found %d entries", len(entries))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected not found after delete, got %d", rec.Code)
+	}
+}
+
+func TestServerBadRequest(t *testing.T) {
+	queue := jobs.NewQueue(1, 1)
+	defer queue.Close()
+	fn := func(ctx context.Context, req Request, progress jobs.ProgressFunc) error { return nil }
+	handler := New(queue, t.TempDir(), fn, silentLogger()).Handler()
+
+	tcs := []struct {
+		name   string
+		req    *http.Request
+		expect string
+	}{
+		{name: "missing file", req: upload(t, "", "", map[string]string{"language": "es"}), expect: "missing subtitle file"},
+		{name: "unsupported format", req: upload(t, "episode.txt", testSrt, map[string]string{"language": "es"}), expect: "unsupported subtitle format"},
+		{name: "invalid language", req: upload(t, "episode.srt", testSrt, map[string]string{"language": "klingonese"}), expect: "invalid target language"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tc.req)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tc.expect) {
+				t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServerQueueFull(t *testing.T) {
+	queue := jobs.NewQueue(0, 1)
+	defer queue.Close()
+	release := make(chan struct{})
+	defer close(release)
+	fn := func(ctx context.Context, req Request, progress jobs.ProgressFunc) error {
+		<-release
+		return nil
+	}
+	handler := New(queue, t.TempDir(), fn, silentLogger()).Handler()
+
+	// without room to wait a job is only accepted while the worker is idle
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, upload(t, "episode.srt", testSrt, map[string]string{"language": "es"}))
+		codes = append(codes, rec.Code)
+		time.Sleep(20 * time.Millisecond)
+	}
+	if codes[len(codes)-1] != http.StatusServiceUnavailable {
+		t.Errorf("expected the queue to be full, got %v", codes)
+	}
+}
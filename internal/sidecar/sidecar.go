@@ -0,0 +1,207 @@
+// Package sidecar finds and names the subtitle files stored next to videos following the convention
+// of media servers like Jellyfin and Plex, e.g. "Episode.en.srt" or "Episode.en.forced.srt"
+package sidecar
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andresbott/substrans/internal/langtag"
+	"github.com/andresbott/substrans/internal/subsedit"
+)
+
+// videoExt are the extensions of the video files looked for
+var videoExt = map[string]bool{
+	".mkv":  true,
+	".mp4":  true,
+	".m4v":  true,
+	".avi":  true,
+	".mov":  true,
+	".webm": true,
+	".wmv":  true,
+	".ts":   true,
+	".mpg":  true,
+	".mpeg": true,
+}
+
+// carried are the flags copied from the source to the translated file name, "default" is recognized
+// but not carried over since a video only has one default subtitle
+var carried = map[string]bool{
+	"forced": true,
+	"sdh":    true,
+	"hi":     true,
+	"cc":     true,
+}
+
+// Name is the parsed file name of a subtitle of a video
+type Name struct {
+	// File is the name of the subtitle file
+	File string
+	// Base is the name of the video without extension
+	Base string
+	Tag  langtag.Tag
+	// Flags are the carried flags in the order of the file name, e.g. forced or sdh
+	Flags []string
+	Ext   string
+}
+
+// Parse reads the name of a subtitle file of the video with the base name, false is returned if the file
+// does not belong to the video or has no language
+func Parse(base, file string) (Name, bool) {
+	ext := filepath.Ext(file)
+	if !subsedit.IsSubtitle(file) || !strings.HasPrefix(file, base+".") {
+		return Name{}, false
+	}
+	n := Name{File: file, Base: base, Ext: ext}
+	found := false
+	for _, part := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(file, base+"."), ext), ".") {
+		lower := strings.ToLower(part)
+		// "hi" is hindi when it is the first part and hearing impaired after the language
+		if found && carried[lower] || lower == "forced" || lower == "sdh" || lower == "cc" {
+			n.Flags = append(n.Flags, lower)
+			continue
+		}
+		if lower == "default" || found {
+			continue
+		}
+		tag, ok := parseLanguage(part)
+		if ok {
+			n.Tag = tag
+			found = true
+		}
+	}
+	return n, found
+}
+
+func parseLanguage(s string) (langtag.Tag, bool) {
+	if tag, ok := langtag.ParseAlpha3(s); ok {
+		return tag, true
+	}
+	tag, err := langtag.Parse(s)
+	return tag, err == nil
+}
+
+// Rename returns the file name for the same video and flags in another language, only the language code is written
+// as media servers expect unless regional is set, e.g. "Episode.es.srt" or "Episode.es-MX.srt"
+func (n Name) Rename(tag langtag.Tag, regional bool) string {
+	lang := tag.Language
+	if regional {
+		lang = tag.String()
+	}
+	parts := append([]string{n.Base, lang}, n.Flags...)
+	return strings.Join(parts, ".") + n.Ext
+}
+
+// matches returns true if the name is in the language, a tag without region matches all the regions
+func (n Name) matches(tag langtag.Tag) bool {
+	if n.Tag.Language != tag.Language {
+		return false
+	}
+	return (tag.Script == "" || n.Tag.Script == tag.Script) && (tag.Region == "" || n.Tag.Region == tag.Region)
+}
+
+func (n Name) flagKey() string {
+	flags := append([]string{}, n.Flags...)
+	sort.Strings(flags)
+	return strings.Join(flags, ".")
+}
+
+// Missing is a subtitle of a video that has no translation in the target language
+type Missing struct {
+	Video  string
+	Source string
+	// Output is the path of the translated file, next to the source
+	Output string
+	Flags  []string
+}
+
+// Scan walks the library and returns the subtitles in the source language of videos that have no
+// subtitle with the same flags in the target language, see Name.Rename for regional
+func Scan(root string, source, target langtag.Tag, regional bool) ([]Missing, error) {
+	out := []Missing{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != root {
+			return filepath.SkipDir
+		}
+		missing, err := scanDir(path, source, target, regional)
+		if err != nil {
+			return err
+		}
+		out = append(out, missing...)
+		return nil
+	})
+	return out, err
+}
+
+func scanDir(dir string, source, target langtag.Tag, regional bool) ([]Missing, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	videos := []string{}
+	subtitles := []string{}
+	existing := map[string]bool{}
+	for _, e := range entries {
+		switch {
+		case e.IsDir():
+		case videoExt[strings.ToLower(filepath.Ext(e.Name()))]:
+			videos = append(videos, e.Name())
+		case subsedit.IsSubtitle(e.Name()):
+			subtitles = append(subtitles, e.Name())
+			existing[e.Name()] = true
+		}
+	}
+
+	// a subtitle belongs to the video with the longest matching name, e.g. "Show.Part2.en.srt"
+	// belongs to "Show.Part2.mkv" and not to "Show.mkv"
+	names := map[string][]Name{}
+	for _, sub := range subtitles {
+		best := ""
+		for _, video := range videos {
+			base := strings.TrimSuffix(video, filepath.Ext(video))
+			if strings.HasPrefix(sub, base+".") && len(base) > len(best) {
+				best = base
+			}
+		}
+		if n, ok := Parse(best, sub); ok && best != "" {
+			names[best] = append(names[best], n)
+		}
+	}
+
+	out := []Missing{}
+	for _, video := range videos {
+		base := strings.TrimSuffix(video, filepath.Ext(video))
+		translated := map[string]bool{}
+		for _, n := range names[base] {
+			if n.matches(target) {
+				translated[n.flagKey()] = true
+			}
+		}
+		for _, n := range names[base] {
+			key := n.flagKey()
+			// without the region the name can be taken by a subtitle of another region, e.g. es-MX for es-ES
+			output := n.Rename(target, regional)
+			if !n.matches(source) || translated[key] || existing[output] {
+				continue
+			}
+			// only one translation is created for subtitles like Episode.en.srt and Episode.eng.srt
+			translated[key] = true
+			out = append(out, Missing{
+				Video:  filepath.Join(dir, video),
+				Source: filepath.Join(dir, n.File),
+				Output: filepath.Join(dir, output),
+				Flags:  n.Flags,
+			})
+		}
+	}
+	return out, nil
+}
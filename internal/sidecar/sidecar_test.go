@@ -0,0 +1,135 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andresbott/substrans/internal/langtag"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		file  string
+		ok    bool
+		lang  string
+		flags []string
+	}{
+		{file: "Episode.en.srt", ok: true, lang: "en"},
+		{file: "Episode.eng.forced.srt", ok: true, lang: "en", flags: []string{"forced"}},
+		{file: "Episode.pt-BR.sdh.ass", ok: true, lang: "pt-BR", flags: []string{"sdh"}},
+		{file: "Episode.en.default.hi.srt", ok: true, lang: "en", flags: []string{"hi"}},
+		{file: "Episode.hi.srt", ok: true, lang: "hi"},
+		{file: "Episode.English.srt", ok: true, lang: "en"},
+		{file: "Episode.srt", ok: false},
+		{file: "Episode.en.nfo", ok: false},
+		{file: "Other.en.srt", ok: false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.file, func(t *testing.T) {
+			got, ok := Parse("Episode", tc.file)
+			if ok != tc.ok {
+				t.Fatalf("expected ok %v, got %v", tc.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if got.Tag.String() != tc.lang {
+				t.Errorf("expected language %q, got %q", tc.lang, got.Tag.String())
+			}
+			if diff := cmp.Diff(tc.flags, got.Flags); diff != "" {
+				t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+
+	n, _ := Parse("Episode", "Episode.eng.sdh.forced.srt")
+	if got := n.Rename(langtag.Tag{Language: "es"}, false); got != "Episode.es.sdh.forced.srt" {
+		t.Errorf("unexpected name: %s", got)
+	}
+	if got := n.Rename(langtag.Tag{Language: "es", Region: "MX"}, false); got != "Episode.es.sdh.forced.srt" {
+		t.Errorf("expected only the language in the name, got %s", got)
+	}
+	if got := n.Rename(langtag.Tag{Language: "es", Region: "MX"}, true); got != "Episode.es-MX.sdh.forced.srt" {
+		t.Errorf("expected the region in the name, got %s", got)
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"Show/S01/Episode 1.mkv",
+		"Show/S01/Episode 1.en.srt",
+		"Show/S01/Episode 1.en.forced.srt",
+		"Show/S01/Episode 1.es.forced.srt",
+		"Show/S01/Episode 1.eng.srt",
+		"Show/S01/Episode 10.mp4",
+		"Show/S01/Episode 10.es-MX.srt",
+		"Show/S01/Episode 10.en.srt",
+		"Movie/Movie.mkv",
+		"Movie/Movie.Part2.mkv",
+		"Movie/Movie.Part2.en.sdh.srt",
+		"Movie/Movie.de.srt",
+		"Orphan/Orphan.en.srt",
+		".trash/Old.mkv",
+		".trash/Old.en.srt",
+	}
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Scan(root, langtag.Tag{Language: "en"}, langtag.Tag{Language: "es"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Missing{
+		{
+			Video:  filepath.Join(root, "Movie/Movie.Part2.mkv"),
+			Source: filepath.Join(root, "Movie/Movie.Part2.en.sdh.srt"),
+			Output: filepath.Join(root, "Movie/Movie.Part2.es.sdh.srt"),
+			Flags:  []string{"sdh"},
+		},
+		{
+			Video:  filepath.Join(root, "Show/S01/Episode 1.mkv"),
+			Source: filepath.Join(root, "Show/S01/Episode 1.en.srt"),
+			Output: filepath.Join(root, "Show/S01/Episode 1.es.srt"),
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestScanRegion(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"Episode 1.mkv", "Episode 1.en.srt", "Episode 2.mkv", "Episode 2.en.srt", "Episode 2.es.srt"} {
+		if err := os.WriteFile(filepath.Join(root, f), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := langtag.Tag{Language: "es", Region: "ES"}
+
+	// the existing Episode 2.es.srt is not overwritten
+	got, err := Scan(root, langtag.Tag{Language: "en"}, target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Output != filepath.Join(root, "Episode 1.es.srt") {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	got, err = Scan(root, langtag.Tag{Language: "en"}, target, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Output != filepath.Join(root, "Episode 2.es-ES.srt") {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package subsedit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asticode/go-astisub"
+)
+
+// ItemAt returns the original and current text of the item at index, the result is not recorded
+func (t *Editor) ItemAt(index int) (Result, error) {
+	if index < 0 || index >= len(t.subtitles.Items) {
+		return Result{}, fmt.Errorf("index out of range")
+	}
+	item := t.subtitles.Items[index]
+	return Result{
+		Index:   index,
+		StartAt: item.StartAt,
+		EndAt:   item.EndAt,
+		Source:  itemLines(t.originalSubs.Items[index]),
+		Target:  itemLines(item),
+	}, nil
+}
+
+// SetText replaces the text of the item at index with text written by hand, lines are separated by "\n".
+// If the amount of lines does not change the styles of the lines are kept.
+func (t *Editor) SetText(index int, text string) error {
+	if index < 0 || index >= len(t.subtitles.Items) {
+		return fmt.Errorf("index out of range")
+	}
+	item := t.subtitles.Items[index]
+	lines := strings.Split(text, "\n")
+	if len(lines) == len(item.Lines) {
+		for i, line := range lines {
+			setLineText(&item.Lines[i], line)
+		}
+	} else {
+		voice := ""
+		if len(item.Lines) > 0 {
+			voice = item.Lines[0].VoiceName
+		}
+		item.Lines = make([]astisub.Line, 0, len(lines))
+		for _, line := range lines {
+			item.Lines = append(item.Lines, astisub.Line{VoiceName: voice, Items: []astisub.LineItem{{Text: line}}})
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.results[index] = Result{
+		Index:   index,
+		StartAt: item.StartAt,
+		EndAt:   item.EndAt,
+		Source:  itemLines(t.originalSubs.Items[index]),
+		Target:  itemLines(item),
+	}
+	return nil
+}
+
+// setLineText puts the text in the first item of the line and empties the others, keeping their styles
+func setLineText(line *astisub.Line, text string) {
+	if len(line.Items) == 0 {
+		line.Items = []astisub.LineItem{{}}
+	}
+	for i := range line.Items {
+		line.Items[i].Text = ""
+	}
+	line.Items[0].Text = text
+}
+
+// itemLines returns the text of the item with its lines separated by "\n"
+func itemLines(item *astisub.Item) string {
+	lines := make([]string, 0, len(item.Lines))
+	for _, line := range item.Lines {
+		text := ""
+		for _, lineItem := range line.Items {
+			text += lineItem.Text
+		}
+		lines = append(lines, text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sameShape returns true if both items have the same amount of lines and line items
+func sameShape(a, b *astisub.Item) bool {
+	if len(a.Lines) != len(b.Lines) {
+		return false
+	}
+	for i := range a.Lines {
+		if len(a.Lines[i].Items) != len(b.Lines[i].Items) {
+			return false
+		}
+	}
+	return true
+}
+
+// copyLines creates a deep copy of lines including their styles
+func copyLines(lines []astisub.Line) []astisub.Line {
+	out := make([]astisub.Line, len(lines))
+	for i, line := range lines {
+		out[i] = line
+		out[i].Items = make([]astisub.LineItem, len(line.Items))
+		copy(out[i].Items, line.Items)
+	}
+	return out
+}
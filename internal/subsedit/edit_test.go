@@ -0,0 +1,113 @@
+package subsedit
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/google/go-cmp/cmp"
+)
+
+// translatedCopy writes a translation of the file where every text is wrapped in [[ ]]
+func translatedCopy(t *testing.T, path string) string {
+	t.Helper()
+	editor, err := New(path, silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = editor.IterateAndReplace(0, func(_ []astisub.Item, actual astisub.Item, _ []astisub.Item) ([]astisub.Line, error) {
+		for i, line := range actual.Lines {
+			for j := range line.Items {
+				actual.Lines[i].Items[j].Text = "[[" + line.Items[j].Text + "]]"
+			}
+		}
+		return actual.Lines, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "translated"+filepath.Ext(path))
+	if err := editor.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestNewWithTranslation(t *testing.T) {
+	translated := translatedCopy(t, "testData/speakers.ass")
+	editor, err := NewWithTranslation("testData/speakers.ass", translated, silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := editor.ItemAt(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Source != "Where's your report?" || got.Target != "[[Where's your report?]]" {
+		t.Errorf("unexpected item: %+v", got)
+	}
+
+	// the callback gets the original item and context, not the translation
+	err = editor.ReplaceLineWithCallback(1, 1, func(prev []astisub.Item, actual astisub.Item, next []astisub.Item) ([]astisub.Line, error) {
+		if diff := cmp.Diff([]string{"Baraja: Orlando, it's time to change shifts."}, SpeakerText(prev)); diff != "" {
+			t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+		}
+		actual.Lines[0].Items[0].Text = "¿Dónde está tu informe?"
+		return actual.Lines, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = editor.SetText(2, "Mis disculpas,\nsargento Baraja.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "reviewed.ass")
+	if err := editor.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewWithTranslation("testData/speakers.ass", out, silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"[[Orlando, it's time to change shifts.]]",
+		"¿Dónde está tu informe?",
+		"Mis disculpas,\nsargento Baraja.",
+		"[[My thoughts were elsewhere.]]",
+	}
+	for i, w := range want {
+		got, err := reloaded.ItemAt(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Target != w {
+			t.Errorf("item %d: expected %q, got %q", i, w, got.Target)
+		}
+	}
+	if results := editor.Results(); len(results) != 2 || results[1].Source != "My apologies, Sergeant Baraja." {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	// after changing the amount of lines the item can be translated again
+	err = editor.ReplaceLineWithCallback(2, 0, func(_ []astisub.Item, actual astisub.Item, _ []astisub.Item) ([]astisub.Line, error) {
+		actual.Lines[0].Items[0].Text = "Perdone, sargento Baraja."
+		return actual.Lines, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := editor.ItemAt(2); got.Target != "Perdone, sargento Baraja." {
+		t.Errorf("unexpected text after translating again: %q", got.Target)
+	}
+}
+
+func TestNewWithTranslationMismatch(t *testing.T) {
+	_, err := NewWithTranslation("testData/speakers.ass", "testData/overlord.ass", silentLogger())
+	if !errors.Is(err, ErrItemsMismatch) {
+		t.Errorf("expected an error for files with different items, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package subsedit
+
+import (
+	"strings"
+
+	"github.com/asticode/go-astisub"
+)
+
+// StyleFilter selects items by the name of their style, e.g. to leave songs or signs untranslated.
+// Names are compared ignoring case; an empty Include list matches every style.
+type StyleFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Match returns true if the item style is included and not excluded by the filter
+func (f StyleFilter) Match(item *astisub.Item) bool {
+	style := ""
+	if item.Style != nil {
+		style = item.Style.ID
+	}
+	for _, s := range f.Exclude {
+		if strings.EqualFold(s, style) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, s := range f.Include {
+		if strings.EqualFold(s, style) {
+			return true
+		}
+	}
+	return false
+}
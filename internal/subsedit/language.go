@@ -0,0 +1,48 @@
+package subsedit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SetLanguage sets the BCP-47 tag written as language metadata of WebVTT and TTML files
+func (t *Editor) SetLanguage(tag string) {
+	t.language = tag
+}
+
+var ttmlLangRe = regexp.MustCompile(`(<tt\b[^>]*?)\s+xml:lang="[^"]*"`)
+
+// writeWithLanguage writes the subtitles adding the language metadata, returns false if the format
+// has no language metadata
+func (t *Editor) writeWithLanguage(p string) (bool, error) {
+	var buf bytes.Buffer
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".vtt":
+		err := t.subtitles.WriteToWebVTT(&buf)
+		if err != nil {
+			return true, err
+		}
+		// the language is added as an additional header after the WEBVTT line
+		header, body, _ := strings.Cut(buf.String(), "\n\n")
+		lines := []string{}
+		for _, l := range strings.Split(header, "\n") {
+			if !strings.HasPrefix(l, "Language:") {
+				lines = append(lines, l)
+			}
+		}
+		lines = append(lines, "Language: "+t.language)
+		return true, os.WriteFile(p, []byte(strings.Join(lines, "\n")+"\n\n"+body), 0o644)
+	case ".ttml":
+		err := t.subtitles.WriteToTTML(&buf)
+		if err != nil {
+			return true, err
+		}
+		content := ttmlLangRe.ReplaceAllString(buf.String(), "$1")
+		content = strings.Replace(content, "<tt ", `<tt xml:lang="`+t.language+`" `, 1)
+		return true, os.WriteFile(p, []byte(content), 0o644)
+	}
+	return false, nil
+}
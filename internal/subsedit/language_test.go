@@ -0,0 +1,48 @@
+package subsedit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteLanguage(t *testing.T) {
+	editor, err := New("testData/speakers.vtt", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+	editor.SetLanguage("es-ES")
+	dir := t.TempDir()
+
+	tcs := []struct {
+		file string
+		want string
+	}{
+		{file: "out.vtt", want: "WEBVTT\nLanguage: es-ES\n\n"},
+		{file: "out.ttml", want: `<tt xml:lang="es-ES" `},
+		{file: "out.srt", want: "1\n00:01:10,000 --> 00:01:12,000\n"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.file, func(t *testing.T) {
+			path := filepath.Join(dir, tc.file)
+			if err := editor.Write(path); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), tc.want) {
+				t.Errorf("expected %q in:\n%s", tc.want, got)
+			}
+			if strings.Count(string(got), "xml:lang=") > 1 {
+				t.Errorf("expected a single language attribute in:\n%s", got)
+			}
+			// the written file must still be readable
+			if _, err := New(path, silentLogger()); err != nil {
+				t.Errorf("unable to read the written file: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package subsedit
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/asticode/go-astisub"
+)
+
+const (
+	defaultMaxLines = 2
+	defaultMaxWidth = 42
+	// badBreakPenalty is added to the cost of a break that leaves an article at the end of a line
+	// or moves punctuation to the start of the next one
+	badBreakPenalty = 400
+)
+
+// ReflowOptions controls how translated text is distributed over the lines of a subtitle item
+type ReflowOptions struct {
+	// MaxLines is the maximum amount of lines an item can have, defaults to 2
+	MaxLines int
+	// MaxWidth is the preferred maximum amount of characters in a single line, defaults to 42
+	MaxWidth int
+	// Lang is the language code of the text like "es", used to avoid breaking after articles
+	Lang string
+}
+
+func (o ReflowOptions) withDefaults() ReflowOptions {
+	if o.MaxLines <= 0 {
+		o.MaxLines = defaultMaxLines
+	}
+	if o.MaxWidth <= 0 {
+		o.MaxWidth = defaultMaxWidth
+	}
+	return o
+}
+
+// lineBreakRe matches explicit line breaks the model might return: ASS \N or \n, html <br> and newlines
+var lineBreakRe = regexp.MustCompile(`(?i)\\N|<br\s*/?>|\r?\n`)
+
+// noBreakAfter contains, per language code, the words that should not be left at the end of a line
+var noBreakAfter = map[string][]string{
+	"en": {"a", "an", "the", "of", "to", "in", "on", "at", "for", "and", "my", "your", "his", "her", "our", "their"},
+	"es": {"el", "la", "los", "las", "un", "una", "unos", "unas", "de", "del", "al", "y", "a", "en", "mi", "tu", "su", "que"},
+	"fr": {"le", "la", "les", "un", "une", "des", "de", "du", "au", "aux", "et", "à", "en", "mon", "ton", "son", "que"},
+	"de": {"der", "die", "das", "den", "dem", "des", "ein", "eine", "einen", "einem", "einer", "und", "zu", "im", "am"},
+	"it": {"il", "lo", "la", "i", "gli", "le", "un", "uno", "una", "di", "del", "della", "e", "a", "in"},
+	"pt": {"o", "a", "os", "as", "um", "uma", "de", "do", "da", "dos", "das", "e", "em", "no", "na"},
+}
+
+// WrapText splits text into at most opts.MaxLines lines of similar width.
+// Explicit breaks (\N, <br> or newlines) are kept if the resulting lines fit, otherwise the text is re-balanced
+// avoiding to end a line with an article or to start a line with punctuation.
+func WrapText(text string, opts ReflowOptions) []string {
+	opts = opts.withDefaults()
+
+	explicit := []string{}
+	for _, part := range lineBreakRe.Split(text, -1) {
+		part = strings.Join(strings.Fields(part), " ")
+		if part != "" {
+			explicit = append(explicit, part)
+		}
+	}
+	if len(explicit) == 0 {
+		return []string{""}
+	}
+	if len(explicit) > 1 && len(explicit) <= opts.MaxLines && fitsWidth(explicit, opts.MaxWidth) {
+		return explicit
+	}
+
+	words := strings.Fields(strings.Join(explicit, " "))
+	total := textWidth(strings.Join(words, " "))
+	if total <= opts.MaxWidth || len(words) == 1 {
+		return []string{strings.Join(words, " ")}
+	}
+
+	n := int(math.Ceil(float64(total) / float64(opts.MaxWidth)))
+	if n > opts.MaxLines {
+		n = opts.MaxLines
+	}
+	if n > len(words) {
+		n = len(words)
+	}
+	return balance(words, n, opts)
+}
+
+// balance distributes words into n lines minimizing the deviation from the average width,
+// using a simple dynamic programming over the possible break positions.
+func balance(words []string, n int, opts ReflowOptions) []string {
+	avoid := map[string]bool{}
+	for _, w := range noBreakAfter[opts.Lang] {
+		avoid[w] = true
+	}
+
+	target := float64(textWidth(strings.Join(words, " "))) / float64(n)
+	lineCost := func(from, to int) float64 {
+		w := textWidth(strings.Join(words[from:to], " "))
+		diff := float64(w) - target
+		cost := diff * diff
+		if w > opts.MaxWidth {
+			cost += float64(w-opts.MaxWidth) * badBreakPenalty
+		}
+		return cost
+	}
+	breakCost := func(at int) float64 {
+		cost := 0.0
+		if avoid[strings.ToLower(strings.TrimFunc(words[at-1], unicode.IsPunct))] {
+			cost += badBreakPenalty
+		}
+		if r, _ := utf8.DecodeRuneInString(words[at]); unicode.IsPunct(r) && !isOpeningPunct(r) {
+			cost += badBreakPenalty
+		}
+		return cost
+	}
+
+	inf := math.Inf(1)
+	// cost[k][j] is the minimal cost of placing the first j words in k lines
+	cost := make([][]float64, n+1)
+	prev := make([][]int, n+1)
+	for k := range cost {
+		cost[k] = make([]float64, len(words)+1)
+		prev[k] = make([]int, len(words)+1)
+		for j := range cost[k] {
+			cost[k][j] = inf
+		}
+	}
+	cost[0][0] = 0
+	for k := 1; k <= n; k++ {
+		for j := k; j <= len(words); j++ {
+			for i := k - 1; i < j; i++ {
+				if cost[k-1][i] == inf {
+					continue
+				}
+				c := cost[k-1][i] + lineCost(i, j)
+				if i > 0 {
+					c += breakCost(i)
+				}
+				if c < cost[k][j] {
+					cost[k][j] = c
+					prev[k][j] = i
+				}
+			}
+		}
+	}
+
+	lines := make([]string, n)
+	end := len(words)
+	for k := n; k > 0; k-- {
+		start := prev[k][end]
+		lines[k-1] = strings.Join(words[start:end], " ")
+		end = start
+	}
+	return lines
+}
+
+func isOpeningPunct(r rune) bool {
+	switch r {
+	case '¿', '¡', '«', '"', '\'', '(', '[', '-', '—', '“', '‘':
+		return true
+	}
+	return unicode.Is(unicode.Ps, r) || unicode.Is(unicode.Pi, r)
+}
+
+func textWidth(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+func fitsWidth(lines []string, width int) bool {
+	for _, l := range lines {
+		if textWidth(l) > width {
+			return false
+		}
+	}
+	return true
+}
+
+// Reflow re-wraps the text of the selected items matching the style filter into balanced lines, see WrapText.
+// Items that mix several text fragments in one line, e.g. to apply positioning tags, are left untouched.
+func (t *Editor) Reflow(opts ReflowOptions) {
+	for i, item := range t.subtitles.Items {
+		if !t.selected(i) || !isPlainItem(item) {
+			continue
+		}
+		texts := []string{}
+		for _, line := range item.Lines {
+			texts = append(texts, line.Items[0].Text)
+		}
+		// lines of an item are translated one by one, so their split is not kept, only breaks
+		// explicitly returned within a translation are honoured
+		wrapped := WrapText(strings.Join(texts, " "), opts)
+		if strings.Join(wrapped, "\n") == strings.Join(texts, "\n") {
+			continue
+		}
+
+		t.subtitles.Items[i].Lines = styledLines(item.Lines[0], wrapped)
+	}
+}
+
+// styledLines creates one line per text, keeping the voice of the template line and its styling on the first line
+func styledLines(template astisub.Line, texts []string) []astisub.Line {
+	lines := make([]astisub.Line, len(texts))
+	for j, text := range texts {
+		lineItem := astisub.LineItem{Text: text}
+		if j == 0 {
+			lineItem.InlineStyle = template.Items[0].InlineStyle
+			lineItem.Style = template.Items[0].Style
+		}
+		lines[j] = astisub.Line{Items: []astisub.LineItem{lineItem}, VoiceName: template.VoiceName}
+	}
+	return lines
+}
+
+// isPlainItem returns true if every line of the item holds a single text fragment
+func isPlainItem(item *astisub.Item) bool {
+	if len(item.Lines) == 0 {
+		return false
+	}
+	for _, line := range item.Lines {
+		if len(line.Items) != 1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package subsedit
+
+import (
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWrapText(t *testing.T) {
+	tcs := []struct {
+		name string
+		text string
+		opts ReflowOptions
+		want []string
+	}{
+		{
+			name: "short text stays in one line",
+			text: "Where's your report?",
+			want: []string{"Where's your report?"},
+		},
+		{
+			name: "long line is split in balanced lines",
+			text: "El Reino Sagrado de Roble, situado en una península al suroeste del Reino de Re-Estize.",
+			opts: ReflowOptions{Lang: "es"},
+			want: []string{"El Reino Sagrado de Roble, situado en una", "península al suroeste del Reino de Re-Estize."},
+		},
+		{
+			name: "explicit ASS break is honoured",
+			text: `Su territorio está dividido\Nen dos mitades por un golfo.`,
+			want: []string{"Su territorio está dividido", "en dos mitades por un golfo."},
+		},
+		{
+			name: "explicit html break is honoured",
+			text: "Mis disculpas,<br/>sargento Baraja.",
+			want: []string{"Mis disculpas,", "sargento Baraja."},
+		},
+		{
+			name: "too many explicit breaks are re-balanced",
+			text: `Mis\Ndisculpas,\Nsargento\NBaraja.`,
+			opts: ReflowOptions{MaxWidth: 20},
+			want: []string{"Mis disculpas,", "sargento Baraja."},
+		},
+		{
+			name: "do not break after an article",
+			text: "we saw the elephant yesterday",
+			opts: ReflowOptions{MaxWidth: 20, Lang: "en"},
+			want: []string{"we saw the elephant", "yesterday"},
+		},
+		{
+			name: "do not break before punctuation",
+			text: "Tu viens ? Allons-y",
+			opts: ReflowOptions{MaxWidth: 12, Lang: "fr"},
+			want: []string{"Tu viens ?", "Allons-y"},
+		},
+		{
+			name: "respects max lines",
+			text: "one two three four five six seven eight nine ten eleven twelve",
+			opts: ReflowOptions{MaxWidth: 10, MaxLines: 3},
+			want: []string{"one two three four", "five six seven eight", "nine ten eleven twelve"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := WrapText(tc.text, tc.opts)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReflow(t *testing.T) {
+	editor, err := New("testData/withPos.ass", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+
+	editor.Reflow(ReflowOptions{MaxWidth: 30})
+
+	want := [][]astisub.Line{
+		{
+			{Items: []astisub.LineItem{{Text: "I need to validate myself."}}},
+			{Items: []astisub.LineItem{{Text: "And prove who I want to be."}}},
+		},
+		// items with positioning tags are not modified
+		{
+			{Items: []astisub.LineItem{{Text: ""}, {Text: "Syr"}}},
+		},
+	}
+	for i, lines := range want {
+		item, err := editor.GetNthItem(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []astisub.Line{}
+		for _, l := range item.Lines {
+			line := astisub.Line{}
+			for _, li := range l.Items {
+				line.Items = append(line.Items, astisub.LineItem{Text: li.Text})
+			}
+			got = append(got, line)
+		}
+		if diff := cmp.Diff(lines, got); diff != "" {
+			t.Errorf("item %d mismatch (-expected +actual):\n%s", i, diff)
+		}
+	}
+}
+
+func TestReflowFiltered(t *testing.T) {
+	editor, err := New("testData/withPos.ass", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+	editor.SetStyleFilter(StyleFilter{Exclude: []string{"q0"}})
+	editor.Reflow(ReflowOptions{MaxWidth: 30})
+
+	// excluded items keep their lines
+	item, err := editor.GetNthItem(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(item.Lines) != 1 || item.Lines[0].Items[0].Text != "I need to validate myself. And prove who I want to be." {
+		t.Errorf("expected the excluded item to be kept, got %+v", item.Lines)
+	}
+}
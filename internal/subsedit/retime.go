@@ -0,0 +1,208 @@
+package subsedit
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// RetimeOptions controls how items are split and merged after translation
+type RetimeOptions struct {
+	// MaxDuration is the duration above which an item with several sentences is split, defaults to 6s
+	MaxDuration time.Duration
+	// MinDuration is the duration below which an item is considered for merging with its neighbour, defaults to 1.2s
+	MinDuration time.Duration
+	// MaxGap is the maximum silence between two items that can be merged, defaults to 250ms
+	MaxGap time.Duration
+	// MaxCPS is the reading speed in characters per second; items read faster than this are merged
+	// and merges that would exceed it are discarded, defaults to 17
+	MaxCPS float64
+	// Wrap is used to distribute the text of new items into lines
+	Wrap ReflowOptions
+}
+
+func (o RetimeOptions) withDefaults() RetimeOptions {
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = 6 * time.Second
+	}
+	if o.MinDuration <= 0 {
+		o.MinDuration = 1200 * time.Millisecond
+	}
+	if o.MaxGap <= 0 {
+		o.MaxGap = 250 * time.Millisecond
+	}
+	if o.MaxCPS <= 0 {
+		o.MaxCPS = 17
+	}
+	o.Wrap = o.Wrap.withDefaults()
+	return o
+}
+
+// sentenceEndRe matches the end of a sentence followed by a space
+var sentenceEndRe = regexp.MustCompile(`([.!?…。！？]+["'»”]?)\s+`)
+
+// SplitSentences splits text into sentences, keeping the punctuation with each sentence
+func SplitSentences(text string) []string {
+	text = strings.Join(strings.Fields(text), " ")
+	sentences := []string{}
+	last := 0
+	for _, m := range sentenceEndRe.FindAllStringSubmatchIndex(text, -1) {
+		s := strings.TrimSpace(text[last:m[3]])
+		if isAbbreviation(s) {
+			continue
+		}
+		sentences = append(sentences, s)
+		last = m[1]
+	}
+	if rest := strings.TrimSpace(text[last:]); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+var abbreviations = []string{"mr.", "mrs.", "ms.", "dr.", "sr.", "sra.", "st.", "vs.", "etc.", "m.", "mme.", "hr.", "fr."}
+
+func isAbbreviation(sentence string) bool {
+	fields := strings.Fields(strings.ToLower(sentence))
+	if len(fields) == 0 {
+		return false
+	}
+	lastWord := fields[len(fields)-1]
+	for _, a := range abbreviations {
+		if lastWord == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Retime splits long items at sentence boundaries and merges consecutive short items.
+// The time span covered by the split or merged items is kept: a split item shares its original duration
+// proportionally to the length of each part, and a merged item goes from the start of the first to the end of the last.
+// It is meant to run after all items are translated, since it changes the amount of items.
+// Items not matching the style filter or the selection are neither split nor merged.
+func (t *Editor) Retime(opts RetimeOptions) {
+	opts = opts.withDefaults()
+
+	// kept are the items left as they are
+	kept := map[*astisub.Item]bool{}
+	split := []*astisub.Item{}
+	for i, item := range t.subtitles.Items {
+		if !t.selected(i) {
+			kept[item] = true
+			split = append(split, item)
+			continue
+		}
+		split = append(split, splitItem(item, opts)...)
+	}
+
+	merged := []*astisub.Item{}
+	for _, item := range split {
+		if len(merged) > 0 && !kept[merged[len(merged)-1]] && !kept[item] && canMerge(merged[len(merged)-1], item, opts) {
+			merged[len(merged)-1] = mergeItems(merged[len(merged)-1], item, opts)
+			continue
+		}
+		merged = append(merged, item)
+	}
+
+	if len(merged) != len(t.subtitles.Items) {
+		for i, item := range merged {
+			item.Index = i + 1
+		}
+		t.logger.Info("Retimed subtitles", "before", len(t.subtitles.Items), "after", len(merged))
+	}
+	t.subtitles.Items = merged
+}
+
+func itemText(item *astisub.Item) string {
+	texts := []string{}
+	for _, line := range item.Lines {
+		texts = append(texts, line.Items[0].Text)
+	}
+	return strings.Join(texts, " ")
+}
+
+func splitItem(item *astisub.Item, opts RetimeOptions) []*astisub.Item {
+	if !isPlainItem(item) || item.EndAt-item.StartAt <= opts.MaxDuration {
+		return []*astisub.Item{item}
+	}
+	sentences := SplitSentences(itemText(item))
+	if len(sentences) < 2 {
+		return []*astisub.Item{item}
+	}
+
+	// group the sentences in two parts of similar length
+	total := textWidth(strings.Join(sentences, " "))
+	best, bestDiff := 1, total
+	for i := 1; i < len(sentences); i++ {
+		diff := textWidth(strings.Join(sentences[:i], " ")) - textWidth(strings.Join(sentences[i:], " "))
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < bestDiff {
+			best, bestDiff = i, diff
+		}
+	}
+	parts := []string{strings.Join(sentences[:best], " "), strings.Join(sentences[best:], " ")}
+
+	duration := item.EndAt - item.StartAt
+	firstDuration := duration * time.Duration(textWidth(parts[0])) / time.Duration(textWidth(parts[0])+textWidth(parts[1]))
+	first := newItemFrom(item, parts[0], item.StartAt, item.StartAt+firstDuration, opts)
+	second := newItemFrom(item, parts[1], item.StartAt+firstDuration, item.EndAt, opts)
+
+	// parts can still be long, e.g. a 12 seconds monologue
+	return append(splitItem(first, opts), splitItem(second, opts)...)
+}
+
+func canMerge(a, b *astisub.Item, opts RetimeOptions) bool {
+	if !isPlainItem(a) || !isPlainItem(b) {
+		return false
+	}
+	if a.Style != b.Style || a.Lines[0].VoiceName != b.Lines[0].VoiceName {
+		return false
+	}
+	if gap := b.StartAt - a.EndAt; gap < 0 || gap > opts.MaxGap {
+		return false
+	}
+	if !isShort(a, opts) && !isShort(b, opts) {
+		return false
+	}
+
+	text := itemText(a) + " " + itemText(b)
+	if textWidth(text) > opts.Wrap.MaxLines*opts.Wrap.MaxWidth {
+		return false
+	}
+	return cps(text, b.EndAt-a.StartAt) <= opts.MaxCPS
+}
+
+func isShort(item *astisub.Item, opts RetimeOptions) bool {
+	duration := item.EndAt - item.StartAt
+	return duration < opts.MinDuration || cps(itemText(item), duration) > opts.MaxCPS
+}
+
+// cps returns the reading speed needed for text displayed during d
+func cps(text string, d time.Duration) float64 {
+	if d <= 0 {
+		return float64(textWidth(text))
+	}
+	return float64(textWidth(text)) / d.Seconds()
+}
+
+func mergeItems(a, b *astisub.Item, opts RetimeOptions) *astisub.Item {
+	return newItemFrom(a, itemText(a)+" "+itemText(b), a.StartAt, b.EndAt, opts)
+}
+
+// newItemFrom creates a new item with the styling of item and the given text and timing
+func newItemFrom(item *astisub.Item, text string, start, end time.Duration, opts RetimeOptions) *astisub.Item {
+	return &astisub.Item{
+		Comments:    item.Comments,
+		InlineStyle: item.InlineStyle,
+		Region:      item.Region,
+		Style:       item.Style,
+		StartAt:     start,
+		EndAt:       end,
+		Lines:       styledLines(item.Lines[0], WrapText(text, opts.Wrap)),
+	}
+}
@@ -0,0 +1,145 @@
+package subsedit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tcs := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "single sentence",
+			text: "Where's your report?",
+			want: []string{"Where's your report?"},
+		},
+		{
+			name: "several sentences",
+			text: "My apologies, Sergeant. My thoughts were elsewhere! But there are no changes.",
+			want: []string{"My apologies, Sergeant.", "My thoughts were elsewhere!", "But there are no changes."},
+		},
+		{
+			name: "abbreviations do not end a sentence",
+			text: "Ask Mr. Baraja. He knows",
+			want: []string{"Ask Mr. Baraja.", "He knows"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SplitSentences(tc.text)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+type timedText struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+func TestRetime(t *testing.T) {
+	tcs := []struct {
+		name  string
+		items []timedText
+		want  []timedText
+	}{
+		{
+			name: "long item is split at sentence boundary",
+			items: []timedText{
+				{Start: 0, End: 8 * time.Second, Text: "Lleva mucho tiempo esperando aquí. Nadie ha venido a relevarle."},
+			},
+			want: []timedText{
+				{Start: 0, End: 8 * time.Second * 34 / 62, Text: "Lleva mucho tiempo esperando aquí."},
+				{Start: 8 * time.Second * 34 / 62, End: 8 * time.Second, Text: "Nadie ha venido a relevarle."},
+			},
+		},
+		{
+			name: "long item with one sentence is kept",
+			items: []timedText{
+				{Start: 0, End: 8 * time.Second, Text: "Lleva mucho tiempo esperando aquí sin que nadie venga"},
+			},
+			want: []timedText{
+				{Start: 0, End: 8 * time.Second, Text: "Lleva mucho tiempo esperando aquí sin que nadie venga"},
+			},
+		},
+		{
+			name: "short consecutive items are merged",
+			items: []timedText{
+				{Start: 0, End: 800 * time.Millisecond, Text: "¿Orlando?"},
+				{Start: 900 * time.Millisecond, End: 2 * time.Second, Text: "Es tu turno."},
+				{Start: 5 * time.Second, End: 6 * time.Second, Text: "¿Y tu informe?"},
+			},
+			want: []timedText{
+				{Start: 0, End: 2 * time.Second, Text: "¿Orlando? Es tu turno."},
+				{Start: 5 * time.Second, End: 6 * time.Second, Text: "¿Y tu informe?"},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			subs := astisub.NewSubtitles()
+			for _, it := range tc.items {
+				subs.Items = append(subs.Items, &astisub.Item{
+					StartAt: it.Start,
+					EndAt:   it.End,
+					Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: it.Text}}}},
+				})
+			}
+			editor := &Editor{subtitles: subs, logger: silentLogger()}
+			editor.Retime(RetimeOptions{})
+
+			got := []timedText{}
+			for _, item := range editor.subtitles.Items {
+				got = append(got, timedText{Start: item.StartAt, End: item.EndAt, Text: itemText(item)})
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRetimeFiltered(t *testing.T) {
+	song := &astisub.Style{ID: "Song"}
+	subs := astisub.NewSubtitles()
+	for _, it := range []struct {
+		text  string
+		style *astisub.Style
+		start time.Duration
+		end   time.Duration
+	}{
+		{text: "Lleva mucho tiempo esperando aquí. Nadie ha venido a relevarle.", style: song, end: 8 * time.Second},
+		{text: "¿Orlando?", start: 8 * time.Second, end: 8800 * time.Millisecond},
+		{text: "Es tu turno.", style: song, start: 8900 * time.Millisecond, end: 10 * time.Second},
+	} {
+		subs.Items = append(subs.Items, &astisub.Item{
+			StartAt: it.start,
+			EndAt:   it.end,
+			Style:   it.style,
+			Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: it.text}}}},
+		})
+	}
+	editor := &Editor{subtitles: subs, logger: silentLogger(), filter: StyleFilter{Exclude: []string{"song"}}}
+	editor.Retime(RetimeOptions{})
+
+	// excluded items are neither split nor merged with their neighbours
+	got := []string{}
+	for _, item := range editor.subtitles.Items {
+		got = append(got, itemText(item))
+	}
+	want := []string{"Lleva mucho tiempo esperando aquí. Nadie ha venido a relevarle.", "¿Orlando?", "Es tu turno."}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
@@ -0,0 +1,48 @@
+package subsedit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseSelection reads a list of item indexes and ranges like "12,40-45" of a file with total items, ranges are cut
+// at the last item; the result is sorted and without duplicates
+func ParseSelection(s string, total int) ([]int, error) {
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid item %q", part)
+		}
+		if start >= total {
+			return nil, fmt.Errorf("item %d does not exist, the file has %d items", start, total)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(to))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid item range %q", part)
+			}
+			end = min(end, total-1)
+		}
+		for i := start; i <= end; i++ {
+			seen[i] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no items selected")
+	}
+	out := make([]int, 0, len(seen))
+	for i := range seen {
+		out = append(out, i)
+	}
+	sort.Ints(out)
+	return out, nil
+}
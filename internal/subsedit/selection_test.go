@@ -0,0 +1,60 @@
+package subsedit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSelection(t *testing.T) {
+	tcs := []struct {
+		in      string
+		want    []int
+		wantErr string
+	}{
+		{in: "12", want: []int{12}},
+		{in: "12,40-43", want: []int{12, 40, 41, 42, 43}},
+		{in: " 5 , 3-4,4", want: []int{3, 4, 5}},
+		{in: "4-2", wantErr: "invalid item range"},
+		{in: "a", wantErr: "invalid item"},
+		{in: "-3", wantErr: "invalid item"},
+		{in: ",", wantErr: "no items selected"},
+		{in: "48-2000000000", want: []int{48, 49}},
+		{in: "50", wantErr: "item 50 does not exist"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseSelection(tc.in, 50)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetSelection(t *testing.T) {
+	editor, err := New("testData/withPos.ass", silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor.SetStyleFilter(StyleFilter{Exclude: []string{"q1"}})
+	if err := editor.SetSelection([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int{2}, editor.Selected()); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if err := editor.SetSelection([]int{9}); err == nil {
+		t.Errorf("expected error for an item out of range")
+	}
+}
@@ -0,0 +1,30 @@
+package subsedit
+
+import (
+	"strings"
+
+	"github.com/asticode/go-astisub"
+)
+
+// SpeakerText returns the text of every line of the items prefixed with its speaker when known,
+// e.g. "Orlando: Where's your report?", the speaker is read from the ASS Name field or the WebVTT <v> tag
+func SpeakerText(items []astisub.Item) []string {
+	texts := []string{}
+	for _, item := range items {
+		for _, line := range item.Lines {
+			text := ""
+			for _, lineItem := range line.Items {
+				text += lineItem.Text
+			}
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			if line.VoiceName != "" {
+				text = line.VoiceName + ": " + text
+			}
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,53 @@
+package subsedit
+
+import (
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSpeakerText(t *testing.T) {
+	tcs := []struct {
+		name string
+		file string
+		want []string
+	}{
+		{
+			name: "ass name field",
+			file: "testData/speakers.ass",
+			want: []string{
+				"Baraja: Orlando, it's time to change shifts.",
+				"Baraja: Where's your report?",
+				"Orlando: My apologies, Sergeant Baraja.",
+				"Orlando: My thoughts were elsewhere.",
+			},
+		},
+		{
+			name: "vtt voice tag",
+			file: "testData/speakers.vtt",
+			want: []string{
+				"Baraja: Orlando, it's time to change shifts.",
+				"Baraja: Where's your report?",
+				"Orlando: My apologies, Sergeant Baraja.",
+				"My thoughts were elsewhere.",
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			subs, err := astisub.OpenFile(tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			items := []astisub.Item{}
+			for _, item := range subs.Items {
+				items = append(items, *item)
+			}
+			if diff := cmp.Diff(tc.want, SpeakerText(items)); diff != "" {
+				t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package subsedit
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/asticode/go-astisub"
@@ -14,6 +18,32 @@ type Editor struct {
 	subtitles    *astisub.Subtitles
 	originalSubs *astisub.Subtitles
 	logger       *slog.Logger
+	results      map[int]Result
+	workers      int
+	filter       StyleFilter
+	// selection limits the processed items to these indexes, all items are processed if nil
+	selection map[int]bool
+	progress  func(Progress)
+	// language is the BCP-47 tag of the subtitles, written as metadata if the format supports it
+	language string
+	mu       sync.Mutex
+}
+
+// Progress is reported by IterateAndReplace after every processed item
+type Progress struct {
+	Done      int
+	Total     int
+	Remaining time.Duration
+}
+
+// Result holds the original and replaced text of a processed item
+type Result struct {
+	Index    int
+	StartAt  time.Duration
+	EndAt    time.Duration
+	Source   string
+	Target   string
+	Duration time.Duration
 }
 
 type slogWriter struct {
@@ -26,6 +56,21 @@ func (w slogWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// subtitleExt are the extensions of the subtitle formats that can be read
+var subtitleExt = map[string]bool{
+	".srt":  true,
+	".ass":  true,
+	".ssa":  true,
+	".vtt":  true,
+	".stl":  true,
+	".ttml": true,
+}
+
+// IsSubtitle returns true if the file has the extension of a supported subtitle format
+func IsSubtitle(path string) bool {
+	return subtitleExt[strings.ToLower(filepath.Ext(path))]
+}
+
 // New creates a new Editor instance
 func New(filePath string, logger *slog.Logger) (*Editor, error) {
 	// Set the global logger to use slog
@@ -50,10 +95,53 @@ func New(filePath string, logger *slog.Logger) (*Editor, error) {
 		subtitles:    subtitles,
 		originalSubs: originalSubs,
 		logger:       logger,
+		results:      map[int]Result{},
+		workers:      1,
+	}
+	return e, nil
+}
+
+// ErrItemsMismatch is returned by NewWithTranslation when the translated file does not have the items of the original,
+// e.g. because it was retimed
+var ErrItemsMismatch = errors.New("the items of the translation do not match the original")
+
+// NewWithTranslation creates an Editor for a file that was already translated: the original file provides
+// the text and context sent for translation, replacements are applied to the translated file
+func NewWithTranslation(originalPath, translatedPath string, logger *slog.Logger) (*Editor, error) {
+	e, err := New(originalPath, logger)
+	if err != nil {
+		return nil, err
+	}
+	translated, err := astisub.OpenFile(translatedPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading translated subtitle file: %v", err)
+	}
+	if len(translated.Items) != len(e.originalSubs.Items) {
+		return nil, fmt.Errorf("%w: the translated file has %d items but the original has %d",
+			ErrItemsMismatch, len(translated.Items), len(e.originalSubs.Items))
 	}
+	e.subtitles = translated
 	return e, nil
 }
 
+// SetWorkers sets the amount of items processed in parallel by IterateAndReplace
+func (t *Editor) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	t.workers = n
+}
+
+// SetProgress sets a function called by IterateAndReplace after every processed item
+func (t *Editor) SetProgress(fn func(Progress)) {
+	t.progress = fn
+}
+
+// SetStyleFilter limits the items processed by IterateAndReplace to the ones matching the filter
+func (t *Editor) SetStyleFilter(f StyleFilter) {
+	t.filter = f
+}
+
 // GetTotalItems returns the total number of subtitle items
 func (t *Editor) GetTotalItems() int {
 	return len(t.subtitles.Items)
@@ -69,109 +157,241 @@ func (t *Editor) GetNthItem(n int) (*astisub.Item, error) {
 
 type TextReplace func([]astisub.Item, astisub.Item, []astisub.Item) ([]astisub.Line, error)
 
+// TextReplaceAt is like TextReplace but it also receives the index of the item being replaced
+type TextReplaceAt func(int, []astisub.Item, astisub.Item, []astisub.Item) ([]astisub.Line, error)
+
+func (fn TextReplace) at() TextReplaceAt {
+	return func(_ int, prevItems []astisub.Item, actualItem astisub.Item, nextItems []astisub.Item) ([]astisub.Line, error) {
+		return fn(prevItems, actualItem, nextItems)
+	}
+}
+
 // ReplaceLineWithCallback replaces a single line with the string value returned by the callback
 // accepts two parameters: slices of previous and next lines of size constextSize
 func (t *Editor) ReplaceLineWithCallback(index int, contextSize int, callback TextReplace) error {
+	return t.ReplaceLineAt(index, contextSize, callback.at())
+}
+
+// ReplaceLineAt is like ReplaceLineWithCallback but the callback also receives the item index
+func (t *Editor) ReplaceLineAt(index int, contextSize int, callback TextReplaceAt) error {
 	if index < 0 || index >= len(t.subtitles.Items) {
 		return fmt.Errorf("index out of range")
 	}
 
-	// Collect previous items
-	tempPrevItems := []astisub.Item{}
-	if index > 0 { // Ensure there are previous items
-		itemCount := 0
-		for i := index - 1; i >= 0 && itemCount < contextSize; i-- {
-			tempPrevItems = append(tempPrevItems, *t.originalSubs.Items[i])
-			itemCount++
-		}
-	}
-	// Reverse the collected items to maintain original order
-	for i, j := 0, len(tempPrevItems)-1; i < j; i, j = i+1, j-1 {
-		tempPrevItems[i], tempPrevItems[j] = tempPrevItems[j], tempPrevItems[i]
-	}
-	prevItems := tempPrevItems
-
-	// Collect next items
-	nextItems := []astisub.Item{}
-	if index < len(t.subtitles.Items)-1 { // Ensure there are next items
-		itemCount := 0
-		for i := index + 1; i < len(t.subtitles.Items) && itemCount < contextSize; i++ {
-			nextItems = append(nextItems, *t.originalSubs.Items[i])
-			itemCount++
-		}
-	}
-	//spew.Dump("== originial")
-	//spew.Dump(t.originalSubs.Items[index].Lines)
-	//banana := DeepCopyItem(t.subtitles.Items[index])
-	//spew.Dump("== banana")
-	//spew.Dump(banana.Lines)
-
-	//spew.Dump("== nextItems")
-	//spew.Dump(nextItems)
-
-	newLines, err := callback(prevItems, DeepCopyItem(t.subtitles.Items[index]), nextItems)
+	prevItems, nextItems := t.contextAt(index, contextSize)
+	source := t.originalSubs.Items[index]
+	newLines, err := callback(index, prevItems, DeepCopyItem(source), nextItems)
 	if err != nil {
 		return err
 	}
-	//spew.Dump("== newlines")
-	//spew.Dump(newLines)
 
-	if len(newLines) != len(t.subtitles.Items[index].Lines) {
-		return fmt.Errorf("callback returned unexpected amount of lines, want: %d, got: %d", len(t.subtitles.Items[index].Lines), len(newLines))
+	if len(newLines) != len(source.Lines) {
+		return fmt.Errorf("callback returned unexpected amount of lines, want: %d, got: %d", len(source.Lines), len(newLines))
+	}
+	target := t.subtitles.Items[index]
+	if !sameShape(target, source) {
+		// the item was reflowed or edited after a previous translation, it gets the lines of the original again
+		target.Lines = copyLines(source.Lines)
 	}
 
-	text := ""
-	original := ""
-	for i, line := range t.subtitles.Items[index].Lines {
+	text := []string{}
+	original := []string{}
+	for i, line := range target.Lines {
 		if i < len(newLines) {
+			lineText := ""
+			lineOriginal := ""
 			for j := range line.Items {
 				if j < len(newLines[i].Items) {
-					text = text + newLines[i].Items[j].Text
-					original = original + line.Items[j].Text
+					lineText = lineText + newLines[i].Items[j].Text
+					lineOriginal = lineOriginal + source.Lines[i].Items[j].Text
 					line.Items[j].Text = newLines[i].Items[j].Text
 				}
 			}
+			text = append(text, lineText)
+			original = append(original, lineOriginal)
 		}
 	}
-	t.logger.Info("Original", "text", original)
-	t.logger.Info("Translated", "text", text)
+	t.logger.Info("Original", "text", strings.Join(original, " "))
+	t.logger.Info("Translated", "text", strings.Join(text, " "))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.results[index] = Result{
+		Index:   index,
+		StartAt: target.StartAt,
+		EndAt:   target.EndAt,
+		Source:  strings.Join(original, "\n"),
+		Target:  strings.Join(text, "\n"),
+	}
 	return nil
 }
 
+// ContextAt returns a copy of the item at index with up to contextSize original items before and after it,
+// these are the items passed to the callbacks of ReplaceLineAt
+func (t *Editor) ContextAt(index int, contextSize int) ([]astisub.Item, astisub.Item, []astisub.Item, error) {
+	if index < 0 || index >= len(t.subtitles.Items) {
+		return nil, astisub.Item{}, nil, fmt.Errorf("index out of range")
+	}
+	prevItems, nextItems := t.contextAt(index, contextSize)
+	return prevItems, DeepCopyItem(t.originalSubs.Items[index]), nextItems, nil
+}
+
+func (t *Editor) contextAt(index int, contextSize int) ([]astisub.Item, []astisub.Item) {
+	prevItems := []astisub.Item{}
+	for i := max(0, index-contextSize); i < index; i++ {
+		prevItems = append(prevItems, *t.originalSubs.Items[i])
+	}
+	nextItems := []astisub.Item{}
+	for i := index + 1; i < len(t.subtitles.Items) && i <= index+contextSize; i++ {
+		nextItems = append(nextItems, *t.originalSubs.Items[i])
+	}
+	return prevItems, nextItems
+}
+
+// Results returns the result of every processed item ordered by index
+func (t *Editor) Results() []Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Result, 0, len(t.results))
+	for _, r := range t.results {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Index < out[j].Index
+	})
+	return out
+}
+
+// SetSelection limits the items processed by IterateAndReplace to the given indexes
+func (t *Editor) SetSelection(indexes []int) error {
+	t.selection = map[int]bool{}
+	for _, i := range indexes {
+		if i < 0 || i >= len(t.subtitles.Items) {
+			return fmt.Errorf("item %d does not exist, the file has %d items", i, len(t.subtitles.Items))
+		}
+		t.selection[i] = true
+	}
+	return nil
+}
+
+// Selected returns the indexes of the items processed by IterateAndReplace, the ones matching the style filter
+// and the selection
+func (t *Editor) Selected() []int {
+	indexes := []int{}
+	for i := range t.subtitles.Items {
+		if t.selected(i) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// selected tells if the item at index matches the style filter and the selection
+func (t *Editor) selected(index int) bool {
+	if t.selection != nil && !t.selection[index] {
+		return false
+	}
+	return t.filter.Match(t.subtitles.Items[index])
+}
+
+// Estimate returns the time needed to process the amount of items given the average duration of an item
+func (t *Editor) Estimate(average time.Duration, items int) time.Duration {
+	return average * time.Duration(items) / time.Duration(t.workers)
+}
+
 // IterateAndReplace processes each item and logs the progress
 func (t *Editor) IterateAndReplace(contextSize int, callback TextReplace) error {
-	totalItems := len(t.subtitles.Items)
+	return t.IterateAndReplaceAt(contextSize, callback.at())
+}
+
+// IterateAndReplaceAt is like IterateAndReplace but the callback also receives the item index
+func (t *Editor) IterateAndReplaceAt(contextSize int, callback TextReplaceAt) error {
+	indexes := t.Selected()
+	totalItems := len(indexes)
+	if skipped := len(t.subtitles.Items) - totalItems; skipped > 0 && t.selection == nil {
+		t.logger.Info("Skipping items filtered by style", "skipped", skipped)
+	}
+
 	var totalDuration time.Duration
+	var done int
+	var firstErr error
+	var statsMu sync.Mutex
 
-	for i := 0; i < totalItems; i++ {
-		start := time.Now()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < t.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
 
-		err := t.ReplaceLineWithCallback(i, contextSize, callback)
-		if err != nil {
-			return fmt.Errorf("error processing item %d: %w", i, err)
-		}
+				err := t.ReplaceLineAt(i, contextSize, callback)
+				duration := time.Since(start)
+
+				statsMu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("error processing item %d: %w", i, err)
+					}
+					statsMu.Unlock()
+					continue
+				}
+				totalDuration += duration
+				done++
 
-		duration := time.Since(start)
-		totalDuration += duration
+				t.mu.Lock()
+				r := t.results[i]
+				r.Duration = duration
+				t.results[i] = r
+				t.mu.Unlock()
 
-		// Calculate estimated remaining time
-		averageDuration := totalDuration / time.Duration(i+1)
-		estimatedRemaining := averageDuration * time.Duration(totalItems-i-1)
+				estimatedRemaining := t.Estimate(totalDuration/time.Duration(done), totalItems-done)
 
-		t.logger.Info("Stats",
-			"line", i+1,
-			"total", totalItems,
-			"duration", duration,
-			"remaining", estimatedRemaining,
-		)
+				t.logger.Info("Stats",
+					"line", done,
+					"total", totalItems,
+					"duration", duration,
+					"remaining", estimatedRemaining,
+				)
+				if t.progress != nil {
+					t.progress(Progress{Done: done, Total: totalItems, Remaining: estimatedRemaining})
+				}
+				statsMu.Unlock()
+			}
+		}()
 	}
-	return nil
+
+	for _, i := range indexes {
+		statsMu.Lock()
+		failed := firstErr != nil
+		statsMu.Unlock()
+		if failed {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return firstErr
 }
 
 func (t *Editor) Write(p string) error {
+	if t.language != "" {
+		written, err := t.writeWithLanguage(p)
+		if written || err != nil {
+			return err
+		}
+	}
 	return t.subtitles.Write(p)
 }
 
+// FormatTime formats a subtitle timestamp as hh:mm:ss.mmm
+func FormatTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
 // DeepCopyItem creates a deep copy of an astisub.Item
 func DeepCopyItem(item *astisub.Item) astisub.Item {
 	itemCopy := astisub.Item{
@@ -181,7 +401,8 @@ func DeepCopyItem(item *astisub.Item) astisub.Item {
 
 	for i, line := range item.Lines {
 		newLine := astisub.Line{
-			Items: make([]astisub.LineItem, len(line.Items)),
+			Items:     make([]astisub.LineItem, len(line.Items)),
+			VoiceName: line.VoiceName,
 		}
 		for j, item := range line.Items {
 			newLine.Items[j] = astisub.LineItem{
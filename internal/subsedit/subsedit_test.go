@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp/cmpopts"
 
@@ -318,6 +319,127 @@ func TestIterateAndReplace(t *testing.T) {
 	}
 }
 
+func TestIterateAndReplaceWorkers(t *testing.T) {
+	translator, err := New("testData/overlord.ass", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+	translator.SetWorkers(4)
+
+	callback := func(prevItems []astisub.Item, actualItem astisub.Item, nextItems []astisub.Item) ([]astisub.Line, error) {
+		out := []astisub.Line{}
+		for _, line := range actualItem.Lines {
+			newLine := astisub.Line{Items: []astisub.LineItem{}}
+			for _, item := range line.Items {
+				newLine.Items = append(newLine.Items, astisub.LineItem{Text: "[[" + item.Text + "]]"})
+			}
+			out = append(out, newLine)
+		}
+		return out, nil
+	}
+
+	err = translator.IterateAndReplace(1, callback)
+	if err != nil {
+		t.Fatalf("Failed to iterate and replace: %v", err)
+	}
+
+	var buf strings.Builder
+	err = translator.subtitles.WriteToSSA(&buf)
+	if err != nil {
+		t.Fatalf("Failed to write subtitles to buffer: %v", err)
+	}
+	want, err := os.ReadFile("testData/overlord_modified.ass")
+	if err != nil {
+		t.Fatalf("Failed to read original file: %v", err)
+	}
+	if diff := cmp.Diff(string(want), buf.String()); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if got := len(translator.Results()); got != translator.GetTotalItems() {
+		t.Errorf("expected %d results, got %d", translator.GetTotalItems(), got)
+	}
+}
+
+func TestIterateAndReplaceStyleFilter(t *testing.T) {
+	translator, err := New("testData/withPos.ass", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+	translator.SetStyleFilter(StyleFilter{Exclude: []string{"q1"}})
+
+	visited := []int{}
+	callback := func(index int, prevItems []astisub.Item, actualItem astisub.Item, nextItems []astisub.Item) ([]astisub.Line, error) {
+		visited = append(visited, index)
+		return actualItem.Lines, nil
+	}
+	err = translator.IterateAndReplaceAt(1, callback)
+	if err != nil {
+		t.Fatalf("Failed to iterate and replace: %v", err)
+	}
+	if diff := cmp.Diff([]int{0, 2, 3}, visited); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestIterateAndReplaceSpeakers(t *testing.T) {
+	translator, err := New("testData/speakers.ass", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+
+	speakers := []string{}
+	callback := func(index int, prevItems []astisub.Item, actualItem astisub.Item, nextItems []astisub.Item) ([]astisub.Line, error) {
+		speakers = append(speakers, actualItem.Lines[0].VoiceName)
+		return actualItem.Lines, nil
+	}
+	err = translator.IterateAndReplaceAt(1, callback)
+	if err != nil {
+		t.Fatalf("Failed to iterate and replace: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Baraja", "Baraja", "Orlando", "Orlando"}, speakers); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
 func silentLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
 }
+
+func TestSelectedAndEstimate(t *testing.T) {
+	editor, err := New("testData/withPos.ass", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+	editor.SetStyleFilter(StyleFilter{Exclude: []string{"q1"}})
+	if diff := cmp.Diff([]int{0, 2, 3}, editor.Selected()); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	editor.SetWorkers(2)
+	if got := editor.Estimate(3*time.Second, 10); got != 15*time.Second {
+		t.Errorf("expected 15s, got %s", got)
+	}
+}
+
+func TestIterateAndReplaceProgress(t *testing.T) {
+	editor, err := New("testData/speakers.ass", silentLogger())
+	if err != nil {
+		t.Fatalf("Failed to create Editor: %v", err)
+	}
+	done := []int{}
+	editor.SetProgress(func(p Progress) {
+		if p.Total != 4 {
+			t.Errorf("expected 4 items in total, got %d", p.Total)
+		}
+		done = append(done, p.Done)
+	})
+	err = editor.IterateAndReplace(0, func(_ []astisub.Item, actual astisub.Item, _ []astisub.Item) ([]astisub.Line, error) {
+		return actual.Lines, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int{1, 2, 3, 4}, done); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
@@ -0,0 +1,162 @@
+// Package tmx reads and writes translation memories in the TMX 1.4 format used by CAT tools
+package tmx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Document is a translation memory
+type Document struct {
+	// SourceLang is the language of the source segments, "*all*" if any variant can be the source
+	SourceLang string
+	Units      []Unit
+}
+
+// Unit is a segment in several languages
+type Unit struct {
+	Variants []Variant
+}
+
+// Variant is the text of a unit in one language
+type Variant struct {
+	Lang string
+	Text string
+}
+
+// Text returns the text of the unit in lang, languages are compared without case
+func (u Unit) Text(lang string) (string, bool) {
+	for _, v := range u.Variants {
+		if strings.EqualFold(v.Lang, lang) {
+			return v.Text, true
+		}
+	}
+	return "", false
+}
+
+type xmlTMX struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  xmlHeader `xml:"header"`
+	Units   []xmlTU   `xml:"body>tu"`
+}
+
+type xmlHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	TMF                 string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+}
+
+type xmlTU struct {
+	Variants []xmlTUV `xml:"tuv"`
+}
+
+type xmlTUV struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	// OldLang is the lang attribute of TMX 1.1 and older
+	OldLang string `xml:"lang,attr,omitempty"`
+	Seg     xmlSeg `xml:"seg"`
+}
+
+// xmlSeg only keeps the text of a segment, inline codes like <ph> or <bpt> are dropped
+type xmlSeg struct {
+	Text string `xml:",chardata"`
+}
+
+// Read parses a TMX file
+func Read(path string) (Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Document{}, err
+	}
+	defer f.Close()
+	doc, err := Decode(f)
+	if err != nil {
+		return Document{}, fmt.Errorf("unable to parse tmx %s: %v", path, err)
+	}
+	return doc, nil
+}
+
+// Decode reads a TMX document
+func Decode(r io.Reader) (Document, error) {
+	x := xmlTMX{}
+	err := xml.NewDecoder(r).Decode(&x)
+	if err != nil {
+		return Document{}, err
+	}
+	doc := Document{SourceLang: x.Header.SrcLang}
+	for _, tu := range x.Units {
+		unit := Unit{}
+		for _, tuv := range tu.Variants {
+			lang := tuv.Lang
+			if lang == "" {
+				lang = tuv.OldLang
+			}
+			text := strings.TrimSpace(tuv.Seg.Text)
+			if lang == "" || text == "" {
+				continue
+			}
+			unit.Variants = append(unit.Variants, Variant{Lang: lang, Text: text})
+		}
+		if len(unit.Variants) > 1 {
+			doc.Units = append(doc.Units, unit)
+		}
+	}
+	return doc, nil
+}
+
+// Write stores the document as a TMX file
+func Write(path string, doc Document) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = Encode(f, doc)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Encode writes the document as TMX 1.4
+func Encode(w io.Writer, doc Document) error {
+	x := xmlTMX{
+		Version: "1.4",
+		Header: xmlHeader{
+			CreationTool:        "substrans",
+			CreationToolVersion: "1",
+			SegType:             "sentence",
+			TMF:                 "substrans",
+			AdminLang:           "en",
+			SrcLang:             doc.SourceLang,
+			DataType:            "plaintext",
+		},
+	}
+	for _, u := range doc.Units {
+		tu := xmlTU{}
+		for _, v := range u.Variants {
+			tu.Variants = append(tu.Variants, xmlTUV{Lang: v.Lang, Seg: xmlSeg{Text: v.Text}})
+		}
+		x.Units = append(x.Units, tu)
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(x)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,79 @@
+package tmx
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	doc := Document{
+		SourceLang: "en",
+		Units: []Unit{
+			{Variants: []Variant{{Lang: "en", Text: "Where's your report?"}, {Lang: "es", Text: "¿Dónde está tu informe?"}}},
+			{Variants: []Variant{{Lang: "en", Text: "Tom & Jerry <3"}, {Lang: "pt-BR", Text: "Tom & Jerry <3"}}},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "memory.tmx")
+	err := Write(path, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(doc, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<tuv xml:lang="es">`) {
+		t.Errorf("expected xml:lang attributes, got:\n%s", buf.String())
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// a file as written by a CAT tool, with inline codes, the old lang attribute and a unit without translation
+	in := `<?xml version="1.0" encoding="UTF-8"?>
+<tmx version="1.4">
+  <header creationtool="OmegaT" srclang="EN-US" segtype="sentence" o-tmf="OmegaT TMX" adminlang="EN-US" datatype="plaintext"/>
+  <body>
+    <tu tuid="1">
+      <prop type="x-note">checked</prop>
+      <tuv xml:lang="EN-US"><seg>Press <ph x="1">&lt;b&gt;</ph>start</seg></tuv>
+      <tuv xml:lang="ES-ES"><seg>Pulsa <ph x="1">&lt;b&gt;</ph>inicio</seg></tuv>
+    </tu>
+    <tu>
+      <tuv lang="EN-US"><seg>Good morning</seg></tuv>
+      <tuv lang="DE-DE"><seg>Guten Morgen</seg></tuv>
+    </tu>
+    <tu>
+      <tuv xml:lang="EN-US"><seg>Untranslated</seg></tuv>
+    </tu>
+  </body>
+</tmx>`
+	got, err := Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Document{
+		SourceLang: "EN-US",
+		Units: []Unit{
+			{Variants: []Variant{{Lang: "EN-US", Text: "Press start"}, {Lang: "ES-ES", Text: "Pulsa inicio"}}},
+			{Variants: []Variant{{Lang: "EN-US", Text: "Good morning"}, {Lang: "DE-DE", Text: "Guten Morgen"}}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if text, ok := got.Units[1].Text("de-de"); !ok || text != "Guten Morgen" {
+		t.Errorf("unexpected text: %q", text)
+	}
+}
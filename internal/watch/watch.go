@@ -0,0 +1,315 @@
+// Package watch polls a folder for new subtitle files and translates them according to a set of rules
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andresbott/substrans/internal/langtag"
+	"github.com/andresbott/substrans/internal/subsedit"
+)
+
+// Rule selects the languages a file is translated into, the first rule matching a file is used
+type Rule struct {
+	// Pattern is a glob matched against the file name, e.g. "*.en.srt", all subtitle files match if empty
+	Pattern   string
+	Languages []string
+	Profile   string
+}
+
+func (r Rule) match(name string) bool {
+	if r.Pattern == "" {
+		return true
+	}
+	ok, _ := filepath.Match(r.Pattern, name)
+	return ok
+}
+
+// translated tells if the name ends in one of the languages of the rule, e.g. "Episode.en.es.srt" for es;
+// these are translations written by the watcher or by hand and translating them again would cascade
+func (r Rule) translated(name string) bool {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	i := strings.LastIndex(stem, ".")
+	if i < 0 {
+		return false
+	}
+	suffix, err := langtag.Parse(stem[i+1:])
+	if err != nil {
+		return false
+	}
+	for _, lang := range r.Languages {
+		tag, err := langtag.Parse(lang)
+		if err == nil && tag.Language == suffix.Language {
+			return true
+		}
+	}
+	return false
+}
+
+// Request describes the translation of a file found in the folder
+type Request struct {
+	Input    string
+	Language string
+	Profile  string
+}
+
+// TranslateFunc translates the file of the request and returns the path of the translation
+type TranslateFunc func(ctx context.Context, req Request) (string, error)
+
+// State records the translations done, it is saved after every translation so that a restart
+// does not translate the same files again
+type State struct {
+	// Files are the processed files by their path relative to the folder
+	Files map[string]*FileState `json:"files"`
+}
+
+// FileState holds the translations of a file, they are done again if the file changes
+type FileState struct {
+	Size      int64              `json:"size"`
+	Modified  time.Time          `json:"modified"`
+	Languages map[string]*Output `json:"languages"`
+}
+
+// Output is the result of translating a file into a language, failed translations are not retried
+// until the file changes
+type Output struct {
+	// Path of the translation relative to the folder
+	Path     string    `json:"path,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Finished time.Time `json:"finished"`
+}
+
+// Watcher scans a folder and translates the files matching its rules
+type Watcher struct {
+	dir       string
+	rules     []Rule
+	statePath string
+	translate TranslateFunc
+	log       *slog.Logger
+
+	state State
+	// pending are the files seen in the last scan that were not processed yet, a file is only
+	// translated once its size and modification time did not change between two scans or it
+	// was not modified for the settle time
+	pending map[string]fileInfo
+	settle  time.Duration
+}
+
+// defaultSettle is the time after which an unchanged file is considered complete
+const defaultSettle = 30 * time.Second
+
+type fileInfo struct {
+	size     int64
+	modified time.Time
+}
+
+// New creates a watcher for dir, the state is loaded from statePath if it exists
+func New(dir string, rules []Rule, statePath string, fn TranslateFunc, log *slog.Logger) (*Watcher, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch folder: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("unable to watch folder: %s is not a directory", dir)
+	}
+	dir = filepath.Clean(dir)
+	state, err := loadState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		dir:       dir,
+		rules:     rules,
+		statePath: statePath,
+		translate: fn,
+		log:       log,
+		state:     state,
+		pending:   map[string]fileInfo{},
+		settle:    defaultSettle,
+	}, nil
+}
+
+// Run scans the folder every interval until the context is cancelled
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := w.Scan(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			w.log.Error("scan failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan looks for new or changed subtitle files and translates the ones that are complete
+func (w *Watcher) Scan(ctx context.Context) error {
+	outputs := w.outputs()
+	seen := map[string]fileInfo{}
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != w.dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !subsedit.IsSubtitle(path) || outputs[path] {
+			return nil
+		}
+		rule, ok := w.rule(d.Name())
+		if !ok || rule.translated(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(w.dir, path)
+		if err != nil {
+			return err
+		}
+		current := fileInfo{size: info.Size(), modified: info.ModTime()}
+
+		file, known := w.state.Files[rel]
+		if !known || file.Size != current.size || !file.Modified.Equal(current.modified) {
+			// new or changed files are translated once they stop changing
+			last, ok := w.pending[rel]
+			if (!ok || last != current) && time.Since(current.modified) < w.settle {
+				seen[rel] = current
+				return nil
+			}
+			file = &FileState{Size: current.size, Modified: current.modified, Languages: map[string]*Output{}}
+			w.state.Files[rel] = file
+		}
+		return w.process(ctx, path, file, rule)
+	})
+	w.pending = seen
+	return err
+}
+
+// process translates the file into the languages of the rule that were not done yet
+func (w *Watcher) process(ctx context.Context, path string, file *FileState, rule Rule) error {
+	for _, lang := range rule.Languages {
+		if _, done := file.Languages[lang]; done {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w.log.Info("translating", "file", path, "language", lang)
+		output, err := w.translate(ctx, Request{Input: path, Language: lang, Profile: rule.Profile})
+		if err != nil && ctx.Err() != nil {
+			// interrupted translations are done again on the next run
+			return ctx.Err()
+		}
+		result := &Output{Path: w.relative(output), Finished: time.Now()}
+		if err != nil {
+			w.log.Error("translation failed, it is retried when the file changes", "file", path, "language", lang, "err", err)
+			result.Error = err.Error()
+		}
+		file.Languages[lang] = result
+		err = w.save()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) rule(name string) (Rule, bool) {
+	for _, r := range w.rules {
+		if r.match(name) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// outputs returns the files written by previous translations, they are not translated again
+func (w *Watcher) outputs() map[string]bool {
+	out := map[string]bool{}
+	for _, file := range w.state.Files {
+		for _, o := range file.Languages {
+			switch {
+			case o.Path == "":
+			case filepath.IsAbs(o.Path):
+				out[filepath.Clean(o.Path)] = true
+			default:
+				out[filepath.Join(w.dir, o.Path)] = true
+			}
+		}
+	}
+	return out
+}
+
+// relative returns the path relative to the folder, paths outside of it are kept as they are
+func (w *Watcher) relative(path string) string {
+	if path == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(w.dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// State returns the translations done so far
+func (w *Watcher) State() State {
+	return w.state
+}
+
+func loadState(path string) (State, error) {
+	state := State{Files: map[string]*FileState{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("unable to read watch state: %v", err)
+	}
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return state, fmt.Errorf("unable to parse watch state %s: %v", path, err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]*FileState{}
+	}
+	return state, nil
+}
+
+// save writes the state to a temporary file first so that an interrupted write does not lose it
+func (w *Watcher) save() error {
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to save watch state: %v", err)
+	}
+	tmp := w.statePath + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to save watch state: %v", err)
+	}
+	err = os.Rename(tmp, w.statePath)
+	if err != nil {
+		return fmt.Errorf("unable to save watch state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func silentLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+}
+
+// fakeTranslator writes the translations next to the input and records the requests
+type fakeTranslator struct {
+	requests []string
+	fail     map[string]bool
+}
+
+func (f *fakeTranslator) translate(ctx context.Context, req Request) (string, error) {
+	name := filepath.Base(req.Input)
+	f.requests = append(f.requests, name+":"+req.Language+":"+req.Profile)
+	if f.fail[name] {
+		return "", errors.New("model not found")
+	}
+	ext := filepath.Ext(req.Input)
+	out := strings.TrimSuffix(req.Input, ext) + "." + req.Language + ext
+	return out, os.WriteFile(out, []byte("translated"), 0o644)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, ".substrans-watch.json")
+	writeFile(t, filepath.Join(dir, "show", "ep1.en.srt"), "1")
+	writeFile(t, filepath.Join(dir, "anime", "ep1.ass"), "1")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not a subtitle")
+	writeFile(t, filepath.Join(dir, ".hidden", "ep9.srt"), "hidden")
+
+	rules := []Rule{
+		{Pattern: "*.ass", Languages: []string{"es"}, Profile: "anime"},
+		{Pattern: "*.en.srt", Languages: []string{"es", "de"}},
+	}
+	ft := &fakeTranslator{fail: map[string]bool{"broken.en.srt": true}}
+	w, err := New(dir, rules, statePath, ft.translate, silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// new files are only translated once they did not change between two scans
+	if err := w.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(ft.requests) != 0 {
+		t.Fatalf("expected no translations on the first scan, got %v", ft.requests)
+	}
+	writeFile(t, filepath.Join(dir, "show", "broken.en.srt"), "1")
+	if err := w.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ep1.ass:es:anime", "ep1.en.srt:es:", "ep1.en.srt:de:"}
+	if diff := cmp.Diff(want, ft.requests); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+
+	// the outputs are not translated again and failed files are not retried
+	ft.requests = nil
+	for i := 0; i < 2; i++ {
+		if err := w.Scan(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff([]string{"broken.en.srt:es:", "broken.en.srt:de:"}, ft.requests); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+	if out := w.State().Files[filepath.Join("show", "ep1.en.srt")].Languages["de"]; out.Path != filepath.Join("show", "ep1.en.de.srt") {
+		t.Errorf("unexpected output: %+v", out)
+	}
+	if out := w.State().Files[filepath.Join("show", "broken.en.srt")].Languages["es"]; out.Error != "model not found" {
+		t.Errorf("expected the error to be recorded, got %+v", out)
+	}
+
+	// after a restart the state is loaded and only changed files are translated
+	ft.requests = nil
+	w, err = New(dir, rules, statePath, ft.translate, silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.settle = 0
+	writeFile(t, filepath.Join(dir, "anime", "ep1.ass"), "changed")
+	if err := w.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"ep1.ass:es:anime"}, ft.requests); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestScanSkipsTranslations(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Episode.en.srt"), "1")
+	writeFile(t, filepath.Join(dir, "Episode.en.es.srt"), "1")
+	writeFile(t, filepath.Join(dir, "Episode.es-MX.srt"), "1")
+	writeFile(t, filepath.Join(dir, "Movie.de.srt"), "1")
+
+	// without a pattern every subtitle matches, the ones already in a target language must be skipped
+	ft := &fakeTranslator{}
+	w, err := New(dir, []Rule{{Languages: []string{"spanish", "pt-BR"}}}, filepath.Join(dir, ".state.json"), ft.translate, silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.settle = 0
+	if err := w.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Episode.en.srt:spanish:", "Episode.en.srt:pt-BR:", "Movie.de.srt:spanish:", "Movie.de.srt:pt-BR:"}
+	if diff := cmp.Diff(want, ft.requests); diff != "" {
+		t.Errorf("Mismatch (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestScanCancelled(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, ".substrans-watch.json")
+	writeFile(t, filepath.Join(dir, "ep1.srt"), "1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fn := func(ctx context.Context, req Request) (string, error) {
+		cancel()
+		return "", ctx.Err()
+	}
+	w, err := New(dir, []Rule{{Languages: []string{"es"}}}, statePath, fn, silentLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.settle = 0
+	if err := w.Scan(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the scan to be cancelled, got %v", err)
+	}
+	// the interrupted translation is not recorded so it is done again on the next run
+	state, err := loadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Files) != 0 {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
+
+func TestNewMissingFolder(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing"), nil, "", nil, silentLogger())
+	if err == nil {
+		t.Error("expected an error for a missing folder")
+	}
+}